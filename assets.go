@@ -0,0 +1,195 @@
+package pages
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultAssetsPath is the URL prefix of an AssetDir without one.
+const DefaultAssetsPath = "/assets/"
+
+// AssetDir is a directory of static files served with content-hash
+// fingerprinted URLs, see the asset helper.
+type AssetDir struct {
+	Dir  string `json:"dir"`  // relative to the manifest
+	Path string `json:"path"` // URL prefix, DefaultAssetsPath when empty
+}
+
+// asset is a static file. Names are slash separated and relative to their
+// AssetDir, as in css/site.css.
+type asset struct {
+	name    string
+	file    string
+	url     string // fingerprinted URL
+	hash    string
+	modTime time.Time
+	gzip    string // precompressed variants, if present
+	brotli  string
+}
+
+// loadAssets fingerprints every file of the manifest asset directories.
+func (p *Pages) loadAssets() error {
+	p.assets = map[string]*asset{}
+	p.assetURLs = map[string]*asset{}
+
+	for _, dir := range p.Manifest.Assets {
+		root := p.resolve(dir.Dir)
+		prefix := dir.Path
+		if len(prefix) == 0 {
+			prefix = DefaultAssetsPath
+		}
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+
+		fsys := p.fsys()
+		err := fs.WalkDir(fsys, root, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			ext := filepath.Ext(file)
+			if ext == ".gz" || ext == ".br" {
+				if len(existing(fsys, strings.TrimSuffix(file, ext))) > 0 {
+					// precompressed variant
+					return nil
+				}
+			}
+
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return err
+			}
+			name := filepath.ToSlash(rel)
+			if existing, ok := p.assets[name]; ok {
+				return errors.New("asset " + name + " found in both " + existing.file + " and " + file)
+			}
+
+			content, err := fs.ReadFile(fsys, file)
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(content)
+			a := &asset{
+				name:    name,
+				file:    file,
+				hash:    hex.EncodeToString(sum[:8]),
+				modTime: info.ModTime(),
+				gzip:    existing(fsys, file+".gz"),
+				brotli:  existing(fsys, file+".br"),
+			}
+			ext = path.Ext(name)
+			a.url = prefix + strings.TrimSuffix(name, ext) + "." + a.hash + ext
+
+			p.assets[name] = a
+			p.assetURLs[a.url] = a
+			p.assetURLs[prefix+name] = a
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assetURL returns the fingerprinted URL of an asset name.
+func (p *Pages) assetURL(name string) (string, bool) {
+	a, ok := p.assets[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return name, false
+	}
+	return a.url, true
+}
+
+// checkAssets fails for literal asset names used by templates that don't
+// exist in any asset directory.
+func (p *Pages) checkAssets() error {
+	for name, c := range p.Components {
+		var missing []string
+		names, err := c.literalArgs("asset", 0)
+		if err != nil {
+			return err
+		}
+		for _, s := range names {
+			if _, ok := p.assetURL(s); !ok {
+				missing = append(missing, s)
+			}
+		}
+		if len(missing) > 0 {
+			return errors.New("component " + name + " uses missing assets " + strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
+
+// assetPrefixes returns the URL prefixes of the manifest asset directories.
+func (p *Pages) assetPrefixes() []string {
+	var prefixes []string
+	for _, dir := range p.Manifest.Assets {
+		prefix := dir.Path
+		if len(prefix) == 0 {
+			prefix = DefaultAssetsPath
+		}
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		prefixes = appendUnique(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// serveAsset serves fingerprinted URLs as immutable and the plain names with
+// revalidation. Precompressed variants are used when the client accepts them.
+func (p *Pages) serveAsset(w http.ResponseWriter, req *http.Request) {
+	a, ok := p.assetURLs[req.URL.Path]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	if req.URL.Path == a.url {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("ETag", `"`+a.hash+`"`)
+
+	if ct := mime.TypeByExtension(path.Ext(a.name)); len(ct) > 0 {
+		w.Header().Set("Content-Type", ct)
+	}
+
+	file := a.file
+	if len(a.gzip) > 0 || len(a.brotli) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		accept := req.Header.Get("Accept-Encoding")
+		if len(a.brotli) > 0 && strings.Contains(accept, "br") {
+			file = a.brotli
+			w.Header().Set("Content-Encoding", "br")
+			w.Header().Set("ETag", `"`+a.hash+`-br"`)
+		} else if len(a.gzip) > 0 && strings.Contains(accept, "gzip") {
+			file = a.gzip
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("ETag", `"`+a.hash+`-gz"`)
+		}
+	}
+
+	content, err := fs.ReadFile(p.fsys(), file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, req, a.name, a.modTime, bytes.NewReader(content))
+}
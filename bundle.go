@@ -0,0 +1,139 @@
+package pages
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// BundlePath is where component script and style bundles are served, under
+// Manifest.ComponentsVersion.
+const BundlePath = "/_components/"
+
+// bundle holds the scripts and styles of the components a page uses.
+type bundle struct {
+	hash   string
+	script []byte
+	style  []byte
+}
+
+// usedComponents returns names and every component they include, sorted.
+func (p *Pages) usedComponents(names []string) []string {
+	seen := map[string]bool{}
+	queue := append([]string{}, names...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		c, ok := p.Components[name]
+		if !ok || seen[name] {
+			continue
+		}
+		seen[name] = true
+		queue = append(queue, c.includes...)
+	}
+
+	used := make([]string, 0, len(seen))
+	for name := range seen {
+		used = append(used, name)
+	}
+	sort.Strings(used)
+	return used
+}
+
+// bundleFor builds the bundle of the named components and those they
+// include. Pages using the same components share a bundle. It returns nil if
+// none of them has a script or style.
+func (p *Pages) bundleFor(names []string) *bundle {
+	var script, style strings.Builder
+	for _, name := range p.usedComponents(names) {
+		c := p.Components[name]
+		if len(c.Script) > 0 {
+			script.WriteString("/* " + name + " */\n;(function(){\n" + c.Script + "\n})();\n")
+		}
+		if len(c.Style) > 0 {
+			style.WriteString("/* " + name + " */\n" + c.Style + "\n")
+		}
+	}
+	if script.Len() == 0 && style.Len() == 0 {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(script.String() + "\x00" + style.String()))
+	hash := hex.EncodeToString(sum[:8])
+	if b, ok := p.bundles[hash]; ok {
+		return b
+	}
+	b := &bundle{hash: hash, script: []byte(script.String()), style: []byte(style.String())}
+	p.bundles[hash] = b
+	return b
+}
+
+func (p *Pages) bundleVersion() string {
+	if len(p.ComponentsVersion) > 0 {
+		return p.ComponentsVersion
+	}
+	return "0"
+}
+
+// inject adds the bundle's style before </head> and its script before
+// </body> of a rendered page.
+func (b *bundle) inject(html string, version string) string {
+	if b == nil {
+		return html
+	}
+	url := BundlePath + version + "/" + b.hash
+	if len(b.style) > 0 {
+		html = insertBefore(html, "</head>", `<link rel="stylesheet" href="`+url+`.css">`)
+	}
+	if len(b.script) > 0 {
+		html = insertBefore(html, "</body>", `<script src="`+url+`.js" defer></script>`)
+	}
+	return html
+}
+
+func insertBefore(html, tag, s string) string {
+	i := strings.LastIndex(html, tag)
+	if i < 0 {
+		return html
+	}
+	return html[:i] + s + html[i:]
+}
+
+func (p *Pages) serveBundle(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	if vars["version"] != p.bundleVersion() {
+		http.NotFound(w, req)
+		return
+	}
+
+	file := vars["file"]
+	i := strings.LastIndexByte(file, '.')
+	if i < 0 {
+		http.NotFound(w, req)
+		return
+	}
+	b, ok := p.bundles[file[:i]]
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	var content []byte
+	switch file[i:] {
+	case ".js":
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		content = b.script
+	case ".css":
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		content = b.style
+	default:
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	_, _ = w.Write(content)
+}
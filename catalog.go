@@ -0,0 +1,232 @@
+package pages
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// loadTranslations reads the Manifest.Translations files and merges them into
+// Resources.translations, where GetResource("translations", locale, key)
+// reads them. Values from files take precedence over inline ones.
+func (p *Pages) loadTranslations() error {
+	if len(p.Manifest.Translations) == 0 {
+		return nil
+	}
+
+	resources := map[string]interface{}{}
+	if len(p.Manifest.Resources) > 0 {
+		if err := json.Unmarshal(p.Manifest.Resources, &resources); err != nil {
+			return err
+		}
+	}
+	translations, _ := resources["translations"].(map[string]interface{})
+	if translations == nil {
+		translations = map[string]interface{}{}
+	}
+
+	for locale, name := range p.Manifest.Translations {
+		name = p.resolve(name)
+		file, err := fs.ReadFile(p.fsys(), name)
+		if err != nil {
+			return err
+		}
+		catalog, err := parseCatalog(filepath.Ext(name), file)
+		if err != nil {
+			return errors.New("error parsing translations: " + name + "; " + err.Error())
+		}
+
+		merged, _ := translations[locale].(map[string]interface{})
+		if merged == nil {
+			merged = map[string]interface{}{}
+		}
+		for k, v := range catalog {
+			merged[k] = v
+		}
+		translations[locale] = merged
+	}
+	resources["translations"] = translations
+
+	raw, err := json.Marshal(resources)
+	if err != nil {
+		return err
+	}
+	p.Manifest.Resources = raw
+	return nil
+}
+
+// parseCatalog parses a gettext .po, XLIFF or JSON translation file into
+// flat key value pairs.
+func parseCatalog(ext string, file []byte) (map[string]string, error) {
+	switch strings.ToLower(ext) {
+	case ".po":
+		return parsePO(file)
+	case ".xlf", ".xliff":
+		return parseXLIFF(file)
+	case ".json":
+		var v map[string]interface{}
+		if err := json.Unmarshal(file, &v); err != nil {
+			return nil, err
+		}
+		catalog := map[string]string{}
+		flatten(catalog, "", v)
+		return catalog, nil
+	}
+	return nil, errors.New("unsupported translation file type " + ext)
+}
+
+// flatten joins the keys of nested JSON objects with dots.
+func flatten(catalog map[string]string, prefix string, v map[string]interface{}) {
+	for k, val := range v {
+		switch t := val.(type) {
+		case map[string]interface{}:
+			flatten(catalog, prefix+k+".", t)
+		case string:
+			catalog[prefix+k] = t
+		}
+	}
+}
+
+// parsePO reads msgid and msgstr pairs of a gettext catalog. Fuzzy and
+// untranslated entries and the header are skipped; for plural entries the
+// first form is used. Entries with a msgctxt are keyed msgctxt.msgid, the way
+// nested JSON catalogs are flattened.
+func parsePO(file []byte) (map[string]string, error) {
+	catalog := map[string]string{}
+
+	var ctx, id, str, field string
+	var fuzzy, hasCtx, hasStr bool
+	flush := func() {
+		if len(id) > 0 && hasStr && len(str) > 0 && !fuzzy {
+			if hasCtx {
+				catalog[ctx+"."+id] = str
+			} else {
+				catalog[id] = str
+			}
+		}
+		ctx, id, str, field = "", "", "", ""
+		fuzzy, hasCtx, hasStr = false, false, false
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(file))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case len(line) == 0:
+			flush()
+			continue
+		case strings.HasPrefix(line, "#,"):
+			if hasCtx || len(id) > 0 || hasStr {
+				flush()
+			}
+			fuzzy = strings.Contains(line, "fuzzy")
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, `"`):
+			// continuation of the previous field
+		default:
+			i := strings.IndexByte(line, ' ')
+			if i < 0 {
+				return nil, errors.New("invalid line: " + line)
+			}
+			field, line = line[:i], strings.TrimSpace(line[i+1:])
+			// msgctxt, or msgid without one, starts the next entry
+			if (field == "msgctxt" && (hasCtx || len(id) > 0 || hasStr)) || (field == "msgid" && (len(id) > 0 || hasStr)) {
+				f, name := fuzzy, field
+				flush()
+				fuzzy, field = f, name
+			}
+		}
+
+		s, err := strconv.Unquote(line)
+		if err != nil {
+			return nil, errors.New("invalid string: " + line)
+		}
+		switch field {
+		case "msgctxt":
+			ctx += s
+			hasCtx = true
+		case "msgid":
+			id += s
+		case "msgstr", "msgstr[0]":
+			str += s
+			hasStr = true
+		}
+	}
+	flush()
+	return catalog, scanner.Err()
+}
+
+// parseXLIFF reads the targets of XLIFF 1.2 trans-units and 2.0 units keyed by
+// their resname or id. Units without a target are skipped.
+func parseXLIFF(file []byte) (map[string]string, error) {
+	catalog := map[string]string{}
+	d := xml.NewDecoder(bytes.NewReader(file))
+
+	var id string
+	var target strings.Builder
+	inTarget, hasTarget := false, false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return catalog, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "trans-unit", "unit":
+				id = ""
+				for _, a := range t.Attr {
+					if a.Name.Local == "resname" || (a.Name.Local == "id" && len(id) == 0) {
+						id = a.Value
+					}
+				}
+				target.Reset()
+				hasTarget = false
+			case "target":
+				inTarget, hasTarget = true, true
+			}
+		case xml.CharData:
+			if inTarget {
+				target.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "target":
+				inTarget = false
+			case "trans-unit", "unit":
+				if len(id) > 0 && hasTarget {
+					catalog[id] = target.String()
+				}
+			}
+		}
+	}
+}
+
+// localeChain lists the locales a translation is looked up in: the locale
+// itself, its language without the region and the default locale.
+func (p *Pages) localeChain(locale string) []string {
+	chain := []string{locale}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		chain = append(chain, locale[:i])
+	}
+	if len(p.DefaultLocale) > 0 {
+		chain = append(chain, p.DefaultLocale)
+	}
+	var unique []string
+	for _, l := range chain {
+		unique = appendUnique(unique, l)
+	}
+	return unique
+}
@@ -0,0 +1,141 @@
+package pages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePO(t *testing.T) {
+	po := `# translator comment
+msgid ""
+msgstr ""
+"Content-Type: text/plain; charset=UTF-8\n"
+"Plural-Forms: nplurals=4; plural=(n%100==1 ? 0 : n%100==2 ? 1 : n%100==3 || n%100==4 ? 2 : 3);\n"
+
+msgid "hello"
+msgstr "Zdravo"
+
+#: tpl/home.html:3
+msgid "long"
+msgstr ""
+"first line, "
+"second line"
+
+msgid ""
+"split "
+"id"
+msgstr "razdeljen"
+
+#, fuzzy
+msgid "unsure"
+msgstr "Morda"
+
+#, c-format
+msgid "flagged"
+msgstr "Označen"
+
+msgid "empty"
+msgstr ""
+
+msgid "item"
+msgid_plural "items"
+msgstr[0] "{count} izdelek"
+msgstr[1] "{count} izdelka"
+
+msgctxt "menu"
+msgid "open"
+msgstr "Odpri"
+
+msgctxt "state"
+msgid "open"
+msgstr "Odprto"
+
+msgid "open"
+msgstr "odpri"
+msgid "escaped"
+msgstr "\"quoted\"\ttab"
+`
+	got, err := parsePO([]byte(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"hello":      "Zdravo",
+		"long":       "first line, second line",
+		"split id":   "razdeljen",
+		"flagged":    "Označen",
+		"item":       "{count} izdelek",
+		"menu.open":  "Odpri",
+		"state.open": "Odprto",
+		"open":       "odpri",
+		"escaped":    "\"quoted\"\ttab",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePO = %v, want %v", got, want)
+	}
+
+	if _, err := parsePO([]byte("msgid \"unterminated\n")); err == nil {
+		t.Error("parsePO with an invalid string: no error")
+	}
+}
+
+func TestParseXLIFF(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want map[string]string
+	}{
+		{"1.2", `<?xml version="1.0" encoding="UTF-8"?>
+<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">
+  <file source-language="en" target-language="sl" datatype="plaintext" original="messages">
+    <body>
+      <trans-unit id="1" resname="hello">
+        <source>Hello</source>
+        <target>Zdravo</target>
+      </trans-unit>
+      <trans-unit resname="cart.items" id="2">
+        <source>{count} items</source>
+        <target>{count} izdelkov &amp; več</target>
+      </trans-unit>
+      <trans-unit id="bye">
+        <source>Bye</source>
+        <target>Adijo</target>
+      </trans-unit>
+      <trans-unit id="untranslated">
+        <source>Later</source>
+      </trans-unit>
+    </body>
+  </file>
+</xliff>`, map[string]string{"hello": "Zdravo", "cart.items": "{count} izdelkov & več", "bye": "Adijo"}},
+		{"2.0", `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="en" trgLang="sl">
+  <file id="f1">
+    <unit id="hello">
+      <segment>
+        <source>Hello</source>
+        <target>Zdravo</target>
+      </segment>
+    </unit>
+    <unit id="empty">
+      <segment>
+        <source>Empty</source>
+        <target></target>
+      </segment>
+    </unit>
+  </file>
+</xliff>`, map[string]string{"hello": "Zdravo", "empty": ""}},
+	}
+	for _, test := range tests {
+		got, err := parseXLIFF([]byte(test.file))
+		if err != nil {
+			t.Errorf("parseXLIFF %s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseXLIFF %s = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	if _, err := parseXLIFF([]byte("<xliff><file>")); err == nil {
+		t.Error("parseXLIFF with unclosed elements: no error")
+	}
+}
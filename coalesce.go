@@ -0,0 +1,77 @@
+package pages
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// flight is an upstream call in progress.
+type flight struct {
+	wg   sync.WaitGroup
+	data interface{}
+	err  error
+}
+
+// flightGroup deduplicates identical concurrent upstream calls so that only
+// one network request is made and every waiting render shares its decoded
+// result. Shared results must be treated as read-only.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = map[string]*flight{}
+	}
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		f.wg.Wait()
+		return f.data, f.err
+	}
+	f := new(flight)
+	f.wg.Add(1)
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		f.wg.Done()
+	}()
+
+	f.data, f.err = fn()
+	return f.data, f.err
+}
+
+// flightKey identifies an upstream call by its resolved method, URL, body and
+// headers. Headers are included so calls with different credentials are never
+// shared.
+func flightKey(r Request, method, url string, body []byte) string {
+	var b strings.Builder
+	b.WriteString(method)
+	b.WriteByte(0)
+	b.WriteString(url)
+	b.WriteByte(0)
+	b.Write(body)
+
+	keys := make([]string, 0, len(r.Headers))
+	for k := range r.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte(':')
+		b.WriteString(r.Headers[k])
+	}
+
+	// the same call can be decoded differently
+	b.WriteByte(0)
+	b.WriteString(r.format())
+	return b.String()
+}
@@ -2,47 +2,113 @@ package pages
 
 import (
 	"errors"
-	"github.com/ales6164/raymond"
-	"io/ioutil"
+	"io/fs"
+
+	"github.com/aymerick/raymond/ast"
 )
 
 type Component struct {
 	*Import
-	Template *raymond.Template
+	Template Template
 	//Raw              string
+
+	Script string // content of ComponentPath
+	Style  string // content of StylePath
+
+	engine   TemplateEngine
+	partial  string   // source registered as this component's partial, see Pages.template
+	source   string   // template source
+	includes []string // names of partials the template includes
+}
+
+// handlebars reports whether the component uses the handlebars engine, the
+// syntax walkExpressions understands.
+func (c *Component) handlebars() bool {
+	_, ok := c.engine.(handlebarsEngine)
+	return ok
+}
+
+// literalArgs returns the string literals passed as argument i to the helper
+// name in the component's template.
+func (c *Component) literalArgs(name string, i int) ([]string, error) {
+	if !c.handlebars() {
+		return htmlLiteralArgs(c.source, name, i), nil
+	}
+	var literals []string
+	err := walkExpressions(c.source, func(expr *ast.Expression) {
+		path, ok := expr.Path.(*ast.PathExpression)
+		if !ok || path.Original != name || len(expr.Params) <= i {
+			return
+		}
+		if s, ok := expr.Params[i].(*ast.StringLiteral); ok {
+			literals = append(literals, s.Value)
+		}
+	})
+	return literals, err
 }
 
 func NewComponent(im *Import) (*Component, error) {
+	return NewComponentFS(osFS{}, im)
+}
+
+// NewComponentFS is NewComponent reading the template, script and style from
+// fsys. Import.Engine must be one of the built in engines.
+func NewComponentFS(fsys fs.FS, im *Import) (*Component, error) {
+	engine, err := builtinEngine(im.Engine)
+	if err != nil {
+		return &Component{Import: im}, err
+	}
+	return newComponent(fsys, im, engine)
+}
+
+func newComponent(fsys fs.FS, im *Import, engine TemplateEngine) (*Component, error) {
 	var c = new(Component)
 	c.Import = im
+	c.engine = engine
 
-	fs, err := ioutil.ReadFile(c.TemplatePath)
+	file, err := fs.ReadFile(fsys, c.TemplatePath)
 	if err != nil {
 		return c, err
 	}
-	raw := string(fs)
-	fs = []byte("")
+	raw := string(file)
+	c.source = raw
+	file = []byte("")
 
 	if len(c.ComponentPath) > 0 {
-		fs, err = ioutil.ReadFile(c.ComponentPath)
+		file, err = fs.ReadFile(fsys, c.ComponentPath)
 		if err != nil {
 			return c, err
 		}
+		c.Script = string(file)
 		/*c.RawSelfContained = fs*/
 	}
 
+	if len(c.StylePath) > 0 {
+		file, err = fs.ReadFile(fsys, c.StylePath)
+		if err != nil {
+			return c, err
+		}
+		c.Style = string(file)
+	}
+	file = []byte("")
+
+	c.includes, err = engine.Partials(raw)
+	if err != nil {
+		return c, errors.New("error parsing file: " + c.TemplatePath + "; " + err.Error())
+	}
+
 	if im.Render {
 		if im.OmitTags {
-			raymond.RegisterPartial(c.Name, raw)
+			c.partial = raw
 		} else {
-			raymond.RegisterPartial(c.Name, "<"+c.Name+">"+raw+"</"+c.Name+">")
+			c.partial = "<" + c.Name + ">" + raw + "</" + c.Name + ">"
 		}
 	} else {
-		raymond.RegisterPartial(c.Name, "<"+c.Name+"></"+c.Name+">")
+		c.partial = "<" + c.Name + "></" + c.Name + ">"
 	}
 
 	//raymond.RegisterPartial(c.Name, "<"+c.Name+">"+c.Raw+"</"+c.Name+">")
-	c.Template, err = raymond.Parse(raw)
+	c.Template, err = engine.Parse(raw)
 	raw = ""
 	if err != nil {
 		return c, errors.New("error parsing file: " + c.TemplatePath + "; " + err.Error())
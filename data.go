@@ -0,0 +1,118 @@
+package pages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const fileScheme = "file://"
+
+var ErrNoFixture = errors.New("no fixture for upstream")
+
+// dataFile resolves a file:// Request URL against the manifest directory, the
+// same way Import.TemplatePath is. Interpolated vars must be plain file names.
+func (p *Pages) dataFile(rawURL string, vars map[string]string) (string, error) {
+	var err error
+	name := regex.ReplaceAllStringFunc(strings.TrimPrefix(rawURL, fileScheme), func(s string) string {
+		v := vars[s[1:]]
+		if v == "." || v == ".." || strings.ContainsAny(v, `/\`) {
+			err = fmt.Errorf("invalid value for %s in file request", s)
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return p.resolve(name), nil
+}
+
+// readFile reads a data file in the given request format.
+func readFile(fsys fs.FS, name, format string) (interface{}, error) {
+	file, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return decode(format, file)
+}
+
+// readData reads a JSON or YAML data file, chosen by its extension.
+func readData(fsys fs.FS, name string) (interface{}, error) {
+	file, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(file, &data)
+		data = normalizeYAML(data)
+	default:
+		err = json.Unmarshal(file, &data)
+	}
+	return data, err
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values yaml produces
+// into map[string]interface{} so data can be marshalled to JSON for templates.
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(val)
+		}
+		return m
+	case []interface{}:
+		for i, val := range t {
+			t[i] = normalizeYAML(val)
+		}
+	}
+	return v
+}
+
+// loadFixtures reads the Options.FixturesPath file which maps upstream URLs
+// to local files. File paths are relative to the fixtures file.
+func (p *Pages) loadFixtures() error {
+	p.fixtures = map[string]string{}
+	err := readAndUnmarshal(p.fsys(), p.FixturesPath, &p.fixtures)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(p.FixturesPath)
+	for u, name := range p.fixtures {
+		p.fixtures[u] = p.resolve(name, dir)
+	}
+	return nil
+}
+
+// fixture returns the canned response for an upstream URL. The full URL is
+// matched first, then the URL without its query string. GraphQL requests with
+// an OperationName are matched by URL#OperationName before that, so one
+// endpoint can have a fixture per operation:
+//
+//	{"https://api.example.com/graphql#Items": "items.json"}
+func (p *Pages) fixture(u *url.URL, r Request) (interface{}, error) {
+	withoutQuery := *u
+	withoutQuery.RawQuery = ""
+	keys := []string{u.String(), withoutQuery.String()}
+	if r.Type == RequestGraphQL && len(r.OperationName) > 0 {
+		keys = append([]string{u.String() + "#" + r.OperationName, withoutQuery.String() + "#" + r.OperationName}, keys...)
+	}
+	for _, key := range keys {
+		if name, ok := p.fixtures[key]; ok {
+			if len(r.Format) > 0 {
+				return readFile(p.fsys(), name, r.format())
+			}
+			return readData(p.fsys(), name)
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoFixture, u.String())
+}
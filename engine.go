@@ -0,0 +1,303 @@
+package pages
+
+import (
+	"errors"
+	"html/template"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ales6164/raymond"
+)
+
+// Template engines selected with Manifest.Engine or Import.Engine.
+const (
+	EngineHandlebars = "handlebars"
+	EngineHTML       = "html"
+)
+
+// TemplateEngine parses component templates. Helpers and partials are only
+// known once the router is built, they are added with Template.With.
+type TemplateEngine interface {
+	Parse(source string) (Template, error)
+	// Partials returns the names of the partials source includes.
+	Partials(source string) ([]string, error)
+	// Include returns source rendering the partial name in place, used for
+	// router outlets.
+	Include(name string) string
+}
+
+// Template is a parsed component template.
+type Template interface {
+	// With returns a copy of the template with helpers and partials
+	// registered. Partials are component sources of the same engine.
+	With(helpers map[string]interface{}, partials map[string]string) (Template, error)
+	// Execute renders ctx. data holds per request values helpers read: the
+	// request locale and hydrate, a func() raymond.SafeString building the
+	// hydrate script when a template uses it.
+	Execute(ctx map[string]interface{}, data map[string]interface{}) (string, error)
+}
+
+// templateDataKey holds the data passed to Execute in the context of html
+// templates. It is not a valid field name, so only helpers can read it.
+const templateDataKey = "@data"
+
+// executeData is the data under templateDataKey. It marshals to null, so
+// helpers such as json can still encode the context.
+type executeData map[string]interface{}
+
+func (executeData) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// templateData returns the data an html template was executed with.
+func templateData(ctx map[string]interface{}) map[string]interface{} {
+	data, _ := ctx[templateDataKey].(executeData)
+	return data
+}
+
+// hydrateData runs the hydrate func passed in the data of Execute.
+func hydrateData(v interface{}) raymond.SafeString {
+	if hydrate, ok := v.(func() raymond.SafeString); ok {
+		return hydrate()
+	}
+	return ""
+}
+
+// definer is implemented by engines whose templates can define the partials
+// they include, see Pages.unfilledOutlet.
+type definer interface {
+	Defines(source string) []string
+}
+
+// engines are the built in template engines.
+var engines = map[string]TemplateEngine{
+	EngineHandlebars: handlebarsEngine{},
+	EngineHTML:       htmlEngine{},
+}
+
+// engine returns the engine called name from Options.Engines or the built in
+// engines.
+func (p *Pages) engine(name string) (TemplateEngine, error) {
+	if e, ok := p.Engines[name]; ok {
+		return e, nil
+	}
+	return builtinEngine(name)
+}
+
+func builtinEngine(name string) (TemplateEngine, error) {
+	if len(name) == 0 {
+		name = EngineHandlebars
+	}
+	if e, ok := engines[name]; ok {
+		return e, nil
+	}
+	return nil, errors.New("template engine " + name + " doesn't exist")
+}
+
+// handlebarsEngine is raymond with the variadic helper calls of
+// expandVariadic.
+type handlebarsEngine struct{}
+
+type handlebarsTemplate struct {
+	t *raymond.Template
+}
+
+func (handlebarsEngine) Parse(source string) (Template, error) {
+	source, err := expandVariadic(source)
+	if err != nil {
+		return nil, err
+	}
+	t, err := raymond.Parse(source)
+	return &handlebarsTemplate{t}, err
+}
+
+func (handlebarsEngine) Partials(source string) ([]string, error) {
+	var names []string
+	err := walkPartials(source, func(name string) {
+		names = appendUnique(names, name)
+	})
+	return names, err
+}
+
+func (handlebarsEngine) Include(name string) string {
+	return "{{> " + name + "}}"
+}
+
+func (h *handlebarsTemplate) With(helpers map[string]interface{}, partials map[string]string) (Template, error) {
+	t := h.t.Clone()
+	t.RegisterHelpers(helpers)
+	for name, source := range partials {
+		source, err := expandVariadic(source)
+		if err != nil {
+			return nil, errors.New("partial " + name + ": " + err.Error())
+		}
+		t.RegisterPartial(name, source)
+	}
+	return &handlebarsTemplate{t}, nil
+}
+
+func (h *handlebarsTemplate) Execute(ctx map[string]interface{}, data map[string]interface{}) (string, error) {
+	frame := raymond.NewDataFrame()
+	for k, v := range data {
+		frame.Set(k, v)
+	}
+	return h.t.ExecWith(ctx, frame)
+}
+
+// htmlEngine is html/template with context aware escaping. Components
+// include each other with {{template "name" .}}. Helpers needing the
+// request, such as {{hydrate $}} and {{i18n $ "key"}}, take the root context
+// as their first argument.
+type htmlEngine struct{}
+
+type htmlTemplate struct {
+	source string
+	t      *template.Template
+}
+
+var htmlInclude = regexp.MustCompile(`\{\{-?\s*(?:template|block)\s+"([^"]+)"`)
+
+// Parse only keeps source, html/template needs the helpers to parse.
+func (htmlEngine) Parse(source string) (Template, error) {
+	return &htmlTemplate{source: source}, nil
+}
+
+func (htmlEngine) Partials(source string) ([]string, error) {
+	var names []string
+	for _, m := range htmlInclude.FindAllStringSubmatch(source, -1) {
+		names = appendUnique(names, m[1])
+	}
+	return names, nil
+}
+
+func (htmlEngine) Include(name string) string {
+	return `{{template "` + strings.ReplaceAll(name, `"`, `\"`) + `" .}}`
+}
+
+var htmlDefine = regexp.MustCompile(`\{\{-?\s*(?:define|block)\s+"([^"]+)"`)
+
+// Defines returns the names source defines itself, they need no partial.
+func (htmlEngine) Defines(source string) []string {
+	var names []string
+	for _, m := range htmlDefine.FindAllStringSubmatch(source, -1) {
+		names = appendUnique(names, m[1])
+	}
+	return names
+}
+
+func (h *htmlTemplate) With(helpers map[string]interface{}, partials map[string]string) (Template, error) {
+	funcs := template.FuncMap{}
+	for name, helper := range helpers {
+		if htmlHelper(name, helper) {
+			funcs[name] = helper
+		}
+	}
+	funcs["hydrate"] = func(ctx map[string]interface{}) template.HTML {
+		return template.HTML(hydrateData(templateData(ctx)["hydrate"]))
+	}
+
+	t, err := template.New("").Funcs(funcs).Parse(h.source)
+	if err != nil {
+		return nil, err
+	}
+	for name, source := range partials {
+		if _, err := t.New(name).Parse(source); err != nil {
+			return nil, errors.New("partial " + name + ": " + err.Error())
+		}
+	}
+	return &htmlTemplate{source: h.source, t: t}, nil
+}
+
+// Execute passes data to helpers under templateDataKey of a shallow copy of
+// ctx.
+func (h *htmlTemplate) Execute(ctx map[string]interface{}, data map[string]interface{}) (string, error) {
+	if h.t == nil {
+		return "", errors.New("template has no helpers, see Template.With")
+	}
+	root := make(map[string]interface{}, len(ctx)+1)
+	for k, v := range ctx {
+		root[k] = v
+	}
+	root[templateDataKey] = executeData(data)
+
+	var b strings.Builder
+	err := h.t.Execute(&b, root)
+	return b.String(), err
+}
+
+var (
+	htmlAction = regexp.MustCompile(`(?s)\{\{(.*?)\}\}`)
+	htmlArg    = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`|[^\\s\"`()|]+|[()|]")
+)
+
+// htmlLiteralArgs returns the string literals passed as argument i to calls
+// of the function name in an html template source.
+func htmlLiteralArgs(source, name string, i int) []string {
+	var literals []string
+	for _, action := range htmlAction.FindAllStringSubmatch(source, -1) {
+		args := htmlArg.FindAllString(action[1], -1)
+		for j, arg := range args {
+			if arg != name {
+				continue
+			}
+			for k := 1; k <= i+1 && j+k < len(args); k++ {
+				if a := args[j+k]; a == "(" || a == ")" || a == "|" {
+					break
+				}
+				if k == i+1 {
+					if s, err := strconv.Unquote(args[j+k]); err == nil {
+						literals = append(literals, s)
+					}
+				}
+			}
+		}
+	}
+	return literals
+}
+
+var raymondOptions = reflect.TypeOf((*raymond.Options)(nil))
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// htmlBuiltins are the functions html/template predefines. They are kept over
+// helpers of the same name, eq and friends take any number of arguments there.
+var htmlBuiltins = map[string]bool{
+	"and": true, "call": true, "html": true, "index": true, "slice": true,
+	"js": true, "len": true, "not": true, "or": true, "print": true,
+	"printf": true, "println": true, "urlquery": true,
+	"eq": true, "ge": true, "gt": true, "le": true, "lt": true, "ne": true,
+}
+
+// htmlHelper reports whether helper can be called from html/template:
+// template identifiers only, no raymond options, no built in names and
+// variadic helpers use their expanded names. See Pages.htmlHelpers for the
+// replacements.
+func htmlHelper(name string, helper interface{}) bool {
+	if strings.Contains(name, "__") || !isIdentifier(name) || htmlBuiltins[name] {
+		return false
+	}
+	t := reflect.TypeOf(helper)
+	if t == nil || t.Kind() != reflect.Func {
+		return false
+	}
+	if t.NumOut() != 1 && (t.NumOut() != 2 || t.Out(1) != errorType) {
+		return false
+	}
+	for i := 0; i < t.NumIn(); i++ {
+		if t.In(i) == raymondOptions {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if r != '_' && !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return len(name) > 0
+}
@@ -0,0 +1,100 @@
+package pages
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func htmlSite(t *testing.T, manifest string, files map[string]string) http.Handler {
+	t.Helper()
+	fsys := fstest.MapFS{"pages.json": {Data: []byte(manifest)}}
+	for name, data := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(data)}
+	}
+	p, err := New(&Options{FS: fsys, JsonFilePath: "pages.json", Helpers: map[string]interface{}{
+		"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := p.BuildRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router
+}
+
+func get(t *testing.T, h http.Handler, target string) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s: %d %s", target, w.Code, w.Body.String())
+	}
+	return w.Body.String()
+}
+
+func TestHTMLEngine(t *testing.T) {
+	h := htmlSite(t, `{
+		"engine": "html",
+		"resources": {"translations": {"en": {"hi": "Hello {name}"}}},
+		"defaultLocale": "en",
+		"imports": [
+			{"name": "index", "templatePath": "index.html", "outlets": {"sidebar": "side"}},
+			{"name": "home", "templatePath": "home.html", "render": true, "omitTags": true},
+			{"name": "card", "templatePath": "card.html", "render": true, "omitTags": true},
+			{"name": "side", "templatePath": "side.html", "render": true, "omitTags": true},
+			{"name": "other", "templatePath": "other.html", "render": true, "omitTags": true},
+			{"name": "dump", "templatePath": "dump.html", "render": true, "omitTags": true}
+		],
+		"routes": [
+			{"path": "/", "component": "home", "page": {"title": "</title><script>x</script>", "kind": "b"}},
+			{"path": "/other", "outlets": {"router-outlet": "other", "sidebar": "card"}, "page": {"kind": "z"}},
+			{"path": "/dump", "component": "dump", "page": {"kind": "j"}}
+		]
+	}`, map[string]string{
+		"index.html": `<title>{{.title}}</title><main>{{template "router-outlet" .}}</main><aside>{{template "sidebar" .}}</aside>`,
+		"home.html":  `{{template "card" .}}|{{if eq .kind "a" "b"}}ab{{end}}|{{len .kind}}|{{shout "hi"}}|{{i18n $ "hi" "name" "<b>"}}|<a title="{{.title}}" onclick="f({{.title}})">`,
+		"card.html":  `<p>card {{.kind}}</p>`,
+		"side.html":  `side`,
+		"other.html": `other`,
+		"dump.html":  `<pre>{{json .}}</pre><pre>{{stringify .}}</pre>`,
+	})
+
+	body := get(t, h, "/")
+	for _, want := range []string{
+		`<title>&lt;/title&gt;&lt;script&gt;x&lt;/script&gt;</title>`,
+		`<main><p>card b</p>|ab|1|HI!|Hello &lt;b&gt;|`,
+		`title="&lt;/title&gt;&lt;script&gt;x&lt;/script&gt;"`,
+		`onclick="f(&#34;\u003c/title\u003e\u003cscript\u003ex\u003c/script\u003e&#34;)"`,
+		`<aside>side</aside>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("GET /: missing %s in\n%s", want, body)
+		}
+	}
+
+	body = get(t, h, "/other")
+	if want := `<main>other</main><aside><p>card z</p></aside>`; !strings.Contains(body, want) {
+		t.Errorf("GET /other: missing %s in\n%s", want, body)
+	}
+
+	body = get(t, h, "/dump")
+	if strings.Count(body, `<pre>{&#34;@data&#34;:null,`) != 2 {
+		t.Errorf("GET /dump: want the context encoded twice in\n%s", body)
+	}
+}
+
+func TestHTMLHelperBuiltins(t *testing.T) {
+	for _, name := range []string{"eq", "ne", "lt", "gt", "not", "len", "and", "or", "index", "printf"} {
+		if htmlHelper(name, func(a, b interface{}) bool { return false }) {
+			t.Errorf("htmlHelper(%s) = true, want built in kept", name)
+		}
+	}
+	if !htmlHelper("upper", func(s string) string { return s }) {
+		t.Error("htmlHelper(upper) = false")
+	}
+}
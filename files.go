@@ -0,0 +1,57 @@
+package pages
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// osFS reads from the operating system. Unlike os.DirFS it takes absolute and
+// relative paths as they are, so manifests resolve the same way they always
+// have when Options.FS is not set.
+type osFS struct{}
+
+func (osFS) Open(name string) (fs.File, error)          { return os.Open(name) }
+func (osFS) ReadFile(name string) ([]byte, error)       { return ioutil.ReadFile(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Glob(pattern string) ([]string, error)      { return filepath.Glob(pattern) }
+
+// fsys is the filesystem manifests, templates, components, translations and
+// data files are read from.
+func (p *Pages) fsys() fs.FS {
+	if p.FS != nil {
+		return p.FS
+	}
+	return osFS{}
+}
+
+// resolve makes a manifest path relative to dir, the manifest directory by
+// default. Absolute paths are kept, or taken from the root of Options.FS.
+func (p *Pages) resolve(name string, dir ...string) string {
+	base := p.base
+	if len(dir) > 0 {
+		base = dir[0]
+	}
+	if p.FS != nil {
+		if path.IsAbs(name) {
+			return path.Clean(strings.TrimPrefix(name, "/"))
+		}
+		return path.Join(base, name)
+	}
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(base, name)
+}
+
+// existing returns name if the file exists.
+func existing(fsys fs.FS, name string) string {
+	if _, err := fs.Stat(fsys, name); err != nil {
+		return ""
+	}
+	return name
+}
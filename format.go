@@ -0,0 +1,175 @@
+package pages
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/ales6164/raymond"
+)
+
+const (
+	FormatJSON = "json"
+	FormatXML  = "xml"
+	FormatText = "text"
+	FormatHTML = "html"
+
+	DefaultMaxUpstreamBytes = 10 << 20
+)
+
+var (
+	ErrResponseTooLarge = errors.New("upstream response too large")
+	ErrContentType      = errors.New("unexpected upstream content type")
+	ErrUnknownFormat    = errors.New("unknown request format")
+)
+
+// contentTypes lists the media types accepted for each format. Entries
+// starting with "+" match structured syntax suffixes and entries ending with
+// "/" match a whole top level type.
+var contentTypes = map[string][]string{
+	FormatJSON: {"application/json", "text/json", "+json"},
+	FormatXML:  {"application/xml", "text/xml", "+xml"},
+	FormatText: {"text/"},
+	FormatHTML: {"text/html", "application/xhtml+xml"},
+}
+
+func (r Request) format() string {
+	if len(r.Format) == 0 || r.Type == RequestGraphQL {
+		return FormatJSON
+	}
+	return r.Format
+}
+
+func (p *Pages) maxBytes(r Request) int64 {
+	if r.MaxBytes > 0 {
+		return r.MaxBytes
+	}
+	if p.MaxUpstreamBytes > 0 {
+		return p.MaxUpstreamBytes
+	}
+	return DefaultMaxUpstreamBytes
+}
+
+// checkContentType rejects upstream responses whose Content-Type doesn't match
+// the request format. A missing Content-Type is accepted.
+func checkContentType(format, header string) error {
+	if len(header) == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrContentType, header)
+	}
+	for _, t := range contentTypes[format] {
+		switch {
+		case strings.HasPrefix(t, "+") && strings.HasSuffix(mediaType, t),
+			strings.HasSuffix(t, "/") && strings.HasPrefix(mediaType, t),
+			mediaType == t:
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s for %s request", ErrContentType, mediaType, format)
+}
+
+// readLimited reads at most max bytes from r.
+func readLimited(r io.Reader, max int64) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	n, err := buf.ReadFrom(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > max {
+		return nil, ErrResponseTooLarge
+	}
+	return buf.Bytes(), nil
+}
+
+// decode turns a response body into a value templates can work with. Text
+// is returned as a string, HTML as a raymond.SafeString so it is rendered
+// unescaped and XML as nested maps, see decodeXML.
+func decode(format string, body []byte) (interface{}, error) {
+	switch format {
+	case FormatJSON:
+		var data interface{}
+		err := json.Unmarshal(body, &data)
+		return data, err
+	case FormatXML:
+		return decodeXML(body)
+	case FormatText:
+		return string(body), nil
+	case FormatHTML:
+		return raymond.SafeString(body), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+// decodeXML converts an XML document to maps keyed by element name.
+// Attributes are stored under "-name" and character data next to attributes
+// or child elements under "#text". Elements with only character data become
+// strings and repeated elements become arrays.
+func decodeXML(body []byte) (interface{}, error) {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			v, err := decodeElement(d, start)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{start.Name.Local: v}, nil
+		}
+	}
+}
+
+func decodeElement(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	node := map[string]interface{}{}
+	for _, a := range start.Attr {
+		node["-"+a.Name.Local] = a.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := decodeElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch existing := node[name].(type) {
+			case nil:
+				node[name] = child
+			case []interface{}:
+				node[name] = append(existing, child)
+			default:
+				node[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			s := strings.TrimSpace(text.String())
+			if len(node) == 0 {
+				return s, nil
+			}
+			if len(s) > 0 {
+				node["#text"] = s
+			}
+			return node, nil
+		}
+	}
+}
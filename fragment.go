@@ -0,0 +1,58 @@
+package pages
+
+import (
+	"errors"
+	"net/http"
+)
+
+// FragmentPath prefixes the paths of routes with Fragment set. Requests to
+// FragmentPath plus the route path render the route's component alone.
+const FragmentPath = "/_fragment"
+
+// fragmentComponent returns the component of the last of routes when that
+// route sets Fragment.
+func fragmentComponent(routes []*Route) string {
+	for i := len(routes) - 1; i >= 0; i-- {
+		if len(routes[i].Component) > 0 {
+			if routes[i].Fragment {
+				return routes[i].Component
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// fragmentAllowed reports whether Manifest.Fragments lists the component.
+func (p *Pages) fragmentAllowed(name string) bool {
+	for _, f := range p.Fragments {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// handleFragment serves the component of routes without its layout under
+// FragmentPath.
+func (p *Pages) handleFragment(path string, routes []*Route, render func(Template, bool) http.HandlerFunc) error {
+	name := fragmentComponent(routes)
+	if len(name) == 0 {
+		return nil
+	}
+	if !p.fragmentAllowed(name) {
+		return errors.New("component " + name + " is not listed in fragments")
+	}
+	templ, err := p.template(p.Components[name], nil)
+	if err != nil {
+		return err
+	}
+
+	handler := p.withMiddleware(render(templ, true))
+	if path[len(path)-1:] == "*" {
+		p.fragments.PathPrefix(path[:len(path)-1]).Handler(handler)
+	} else {
+		p.fragments.Handle(path, handler)
+	}
+	return nil
+}
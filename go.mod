@@ -1,12 +1,12 @@
 module github.com/ales6164/pages
 
-go 1.15
+go 1.16
 
 require (
 	github.com/ales6164/raymond v2.0.2+incompatible
-	github.com/aymerick/raymond v2.0.2+incompatible // indirect
+	github.com/aymerick/raymond v2.0.2+incompatible
 	github.com/buger/jsonparser v1.0.0
 	github.com/gorilla/mux v1.7.4
 	github.com/gorilla/sessions v1.2.0
-	gopkg.in/yaml.v2 v2.3.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
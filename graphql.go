@@ -0,0 +1,114 @@
+package pages
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var graphQLVar = regexp.MustCompile(`^\$(\w+)(?::(int|float|bool|string))?$`)
+
+// graphQLError is returned when a GraphQL response carries errors.
+type graphQLError []struct {
+	Message string `json:"message"`
+}
+
+func (e graphQLError) Error() string {
+	messages := make([]string, len(e))
+	for i, m := range e {
+		messages[i] = m.Message
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+// fetchGraphQL posts the request query with its bound variables and returns
+// the data of the response envelope.
+func (p *Pages) fetchGraphQL(r Request, req *http.Request, vars map[string]string) (interface{}, error) {
+	apiUrl, err := url.Parse(resolveURL(r.URL, vars))
+	if err != nil {
+		return nil, err
+	}
+
+	variables, err := bindVariables(r.Variables, vars, upstreamQuery(req))
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":         r.Query,
+		"operationName": r.OperationName,
+		"variables":     variables,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	method := r.Method
+	if len(method) == 0 {
+		method = http.MethodPost
+	}
+
+	resp, err := p.roundTrip(r, method, apiUrl, body)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope, _ := resp.(map[string]interface{})
+	if errs, ok := envelope["errors"].([]interface{}); ok && len(errs) > 0 {
+		var ge graphQLError
+		raw, _ := json.Marshal(errs)
+		_ = json.Unmarshal(raw, &ge)
+		return envelope["data"], ge
+	}
+	return envelope["data"], nil
+}
+
+// bindVariables replaces "$name" values with the mux var of that name or, if
+// there is none, the query parameter. Unbound variables are sent as null.
+// Values are strings unless the variable declares int, float or bool, as in
+// "$id:int"; a value that doesn't parse as the type is a bad request.
+func bindVariables(variables map[string]interface{}, vars map[string]string, query url.Values) (map[string]interface{}, error) {
+	bound := make(map[string]interface{}, len(variables))
+	for k, v := range variables {
+		s, ok := v.(string)
+		if !ok {
+			bound[k] = v
+			continue
+		}
+		m := graphQLVar.FindStringSubmatch(s)
+		if m == nil {
+			bound[k] = v
+			continue
+		}
+		var val string
+		if v, ok := vars[m[1]]; ok {
+			val = v
+		} else if v, ok := query[m[1]]; ok && len(v) > 0 {
+			val = v[0]
+		} else {
+			bound[k] = nil
+			continue
+		}
+		typed, err := typedVariable(val, m[2])
+		if err != nil {
+			return nil, statusError{http.StatusBadRequest}
+		}
+		bound[k] = typed
+	}
+	return bound, nil
+}
+
+func typedVariable(val, typ string) (interface{}, error) {
+	switch typ {
+	case "int":
+		return strconv.ParseInt(val, 10, 64)
+	case "float":
+		return strconv.ParseFloat(val, 64)
+	case "bool":
+		return strconv.ParseBool(val)
+	}
+	return val, nil
+}
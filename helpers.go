@@ -0,0 +1,105 @@
+package pages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ales6164/raymond"
+)
+
+// builtinHelpers are registered globally by raymond and can't be replaced.
+var builtinHelpers = []string{"if", "unless", "with", "each", "log", "lookup", "equal"}
+
+// RegisterHelper adds a custom template helper to this instance. helper must
+// be a function returning a single value; it is validated when BuildRouter
+// is called.
+func (p *Pages) RegisterHelper(name string, helper interface{}) {
+	p.registered[name] = helper
+}
+
+// buildHelpers sets the helpers used by every template of this instance:
+// the package and standard helpers followed by Options.Helpers and registered helpers.
+func (p *Pages) buildHelpers() error {
+	p.helpers = map[string]interface{}{
+		// add json helper
+		"stringify": func(k interface{}) string {
+			d, _ := json.Marshal(k)
+			return string(d)
+		},
+
+		// append string helper
+		"append": func(k1, k2 string) string {
+			return k1 + k2
+		},
+
+		"append3": func(k1, k2, k3 string) string {
+			return k1 + k2 + k3
+		},
+
+		// add translation helper
+		"trans": func(locale string, k string, options *raymond.Options) string {
+			return p.translate(locale, k, options.Hash())
+		},
+
+		"i18n": func(k string, options *raymond.Options) string {
+			return p.translate(p.localeOf(options), k, options.Hash())
+		},
+
+		// route context for client side hydration, see hydrateScript
+		"hydrate": func(options *raymond.Options) raymond.SafeString {
+			return hydrateData(options.Data("hydrate"))
+		},
+
+		// fingerprinted static asset url
+		"asset": func(name string) string {
+			u, _ := p.assetURL(name)
+			return u
+		},
+	}
+
+	for name, helper := range stdHelpers() {
+		p.helpers[name] = helper
+	}
+	for name, helper := range p.localeHelpers() {
+		p.helpers[name] = helper
+	}
+
+	p.htmlFuncs = p.htmlHelpers()
+
+	for _, custom := range []map[string]interface{}{p.Options.Helpers, p.registered} {
+		for name, helper := range custom {
+			if err := validateHelper(name, helper); err != nil {
+				return err
+			}
+			// variadic helpers are registered as name__N, see expandVariadic
+			_, variadic := variadicHelpers[name]
+			if _, ok := p.helpers[name]; ok || variadic || strings.Contains(name, "__") {
+				return fmt.Errorf("helper %s already registered", name)
+			}
+			p.helpers[name] = helper
+		}
+	}
+	return nil
+}
+
+func validateHelper(name string, helper interface{}) error {
+	if len(name) == 0 {
+		return errors.New("helper name is empty")
+	}
+	for _, b := range builtinHelpers {
+		if name == b {
+			return fmt.Errorf("helper %s is a builtin helper", name)
+		}
+	}
+	t := reflect.TypeOf(helper)
+	if t == nil || t.Kind() != reflect.Func {
+		return fmt.Errorf("helper %s must be a function", name)
+	}
+	if t.NumOut() != 1 {
+		return fmt.Errorf("helper %s must return a single value", name)
+	}
+	return nil
+}
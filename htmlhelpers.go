@@ -0,0 +1,81 @@
+package pages
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// htmlHelpers replace the helpers taking *raymond.Options in html templates.
+// They take the root context first to find the request locale, and name
+// value pairs where handlebars has hash arguments:
+//
+//	{{i18n $ "cart.items" "count" 3}}
+//	{{formatNumber $ .price "decimals" 2}}
+func (p *Pages) htmlHelpers() map[string]interface{} {
+	locale := func(ctx map[string]interface{}) string {
+		if l, ok := templateData(ctx)["locale"].(string); ok && len(l) > 0 {
+			return l
+		}
+		return p.DefaultLocale
+	}
+	decimals := func(hash map[string]interface{}) int {
+		if d, ok := hash["decimals"]; ok {
+			return toInt(d)
+		}
+		return -1
+	}
+	return map[string]interface{}{
+		"trans": func(locale string, k string, args ...interface{}) (string, error) {
+			hash, err := hashArgs(args)
+			return p.translate(locale, k, hash), err
+		},
+		"i18n": func(ctx map[string]interface{}, k string, args ...interface{}) (string, error) {
+			hash, err := hashArgs(args)
+			return p.translate(locale(ctx), k, hash), err
+		},
+		"formatNumber": func(ctx map[string]interface{}, v interface{}, args ...interface{}) (string, error) {
+			hash, err := hashArgs(args)
+			return formatNumber(v, decimals(hash), locale(ctx)), err
+		},
+		"formatCurrency": func(ctx map[string]interface{}, v interface{}, currency string) string {
+			return formatCurrency(v, currency, locale(ctx))
+		},
+		"formatPercent": func(ctx map[string]interface{}, v interface{}, args ...interface{}) (string, error) {
+			hash, err := hashArgs(args)
+			return formatPercent(v, decimals(hash), locale(ctx)), err
+		},
+		"formatLocalDate": func(ctx map[string]interface{}, v interface{}, args ...interface{}) (string, error) {
+			hash, err := hashArgs(args)
+			t, ok := parseTime(v, "")
+			if !ok {
+				return "", err
+			}
+			style, _ := hash["style"].(string)
+			return formatLocalDate(t, style, locale(ctx)), err
+		},
+		"relativeTime": func(ctx map[string]interface{}, v interface{}) string {
+			t, ok := parseTime(v, "")
+			if !ok {
+				return ""
+			}
+			return formatRelativeTime(t, time.Now(), locale(ctx))
+		},
+	}
+}
+
+// hashArgs pairs up name value arguments.
+func hashArgs(args []interface{}) (map[string]interface{}, error) {
+	if len(args)%2 != 0 {
+		return nil, errors.New("arguments must be name value pairs")
+	}
+	hash := make(map[string]interface{}, len(args)/2)
+	for i := 0; i < len(args); i += 2 {
+		name, ok := args[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("argument name %v is not a string", args[i])
+		}
+		hash[name] = args[i+1]
+	}
+	return hash, nil
+}
@@ -0,0 +1,86 @@
+package pages
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/ales6164/raymond"
+)
+
+// HydrateID is the id of the script element written by the hydrate helper.
+const HydrateID = "page-context"
+
+// defaultHydrate are the context keys hydrated for routes without Hydrate,
+// in addition to the keys of their Page.
+var defaultHydrate = []string{"query", "locale", "data"}
+
+// hydrateKeys returns the context keys the last of routes setting Hydrate
+// allows on the client.
+func hydrateKeys(routes []*Route) []string {
+	var keys []string
+	var page []string
+	for _, route := range routes {
+		if route.Hydrate != nil {
+			keys = route.Hydrate
+		}
+		for k := range route.Page {
+			page = appendUnique(page, k)
+		}
+	}
+	if keys == nil {
+		keys = append(page, defaultHydrate...)
+	}
+	return keys
+}
+
+// hydrateScript returns a script element holding the keys of ctx as JSON.
+// A key may be a dotted path into nested objects, such as storage.menu.
+// json.Marshal escapes <, > and &, so the content can't close the element.
+func hydrateScript(ctx map[string]interface{}, keys []string) (raymond.SafeString, error) {
+	object := map[string]interface{}{}
+	for _, key := range keys {
+		if v, ok := lookupPath(ctx, key); ok {
+			setPath(object, key, v)
+		}
+	}
+	b, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	return raymond.SafeString(`<script type="application/json" id="` + HydrateID + `">` + string(b) + `</script>`), nil
+}
+
+func lookupPath(ctx map[string]interface{}, key string) (interface{}, bool) {
+	var v interface{} = ctx
+	var ok bool
+	for _, part := range strings.Split(key, ".") {
+		switch m := v.(type) {
+		case map[string]interface{}:
+			if v, ok = m[part]; !ok {
+				return nil, false
+			}
+		case map[string]string:
+			s, ok := m[part]
+			if !ok {
+				return nil, false
+			}
+			v = s
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func setPath(object map[string]interface{}, key string, v interface{}) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := object[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			object[part] = next
+		}
+		object = next
+	}
+	object[parts[len(parts)-1]] = v
+}
@@ -0,0 +1,57 @@
+package pages
+
+import "errors"
+
+// DefaultLayoutOutlet is the outlet of a parent layout a layout renders into
+// when its import sets no ParentOutlet. Outlets are partials shared by the
+// whole chain, so with three or more layouts every layout with a parent needs
+// its own ParentOutlet.
+const DefaultLayoutOutlet = "layout-outlet"
+
+// routeLayout returns the layout of the deepest route setting one, so child
+// routes override the layout of their parents.
+func routeLayout(routes []*Route) string {
+	var layout = DefaultLayout
+	for _, route := range routes {
+		if route != nil && len(route.Layout) > 0 {
+			layout = route.Layout
+		}
+	}
+	return layout
+}
+
+// layoutChain returns layout and the parent layouts it renders into, the
+// outermost first.
+func (p *Pages) layoutChain(layout *Component) ([]*Component, error) {
+	var chain = []*Component{layout}
+	var outlets = map[string]bool{}
+	for c := layout; len(c.Parent) > 0; {
+		parent, ok := p.Components[c.Parent]
+		if !ok {
+			return nil, errors.New("parent layout " + c.Parent + " of " + c.Name + " doesn't exist")
+		}
+		if parent.Engine != layout.Engine {
+			return nil, errors.New("layout " + c.Name + " uses engine " + c.Engine + ", parent " + parent.Name + " uses " + parent.Engine)
+		}
+		for _, l := range chain {
+			if l == parent {
+				return nil, errors.New("layout " + parent.Name + " is its own parent")
+			}
+		}
+		outlet := c.parentOutlet()
+		if outlets[outlet] {
+			return nil, errors.New("layout " + layout.Name + " renders into outlet " + outlet + " more than once, set a different parentOutlet on " + c.Name)
+		}
+		outlets[outlet] = true
+		chain = append([]*Component{parent}, chain...)
+		c = parent
+	}
+	return chain, nil
+}
+
+func (c *Component) parentOutlet() string {
+	if len(c.ParentOutlet) > 0 {
+		return c.ParentOutlet
+	}
+	return DefaultLayoutOutlet
+}
@@ -0,0 +1,55 @@
+package pages
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestUnfilledOutlet(t *testing.T) {
+	tests := []struct {
+		layout  string
+		outlets string
+		err     string
+	}{
+		{`{{template "router-outlet" .}}{{template "sidebar" .}}`, `{}`, "outlet sidebar of layout index is not filled"},
+		{`{{template "router-outlet" .}}{{template "sidebar" .}}`, `{"sidebar": ""}`, ""},
+		{`{{template "router-outlet" .}}{{template "sidebar" .}}`, `{"sidebar": "home"}`, ""},
+		{`{{template "router-outlet" .}}{{block "sidebar" .}}none{{end}}`, `{}`, ""},
+		{`{{define "sidebar"}}none{{end}}{{template "router-outlet" .}}{{template "sidebar" .}}`, `{}`, ""},
+		{`{{template "router-outlet" .}}{{template "home" .}}`, `{}`, ""},
+	}
+	for _, test := range tests {
+		fsys := fstest.MapFS{
+			"pages.json": {Data: []byte(`{
+				"engine": "html",
+				"resources": {},
+				"imports": [
+					{"name": "index", "templatePath": "index.html", "outlets": ` + test.outlets + `},
+					{"name": "home", "templatePath": "home.html", "render": true},
+					{"name": "base", "templatePath": "base.html"},
+					{"name": "shop", "templatePath": "shop.html", "parent": "base"}
+				],
+				"routes": [
+					{"path": "/", "component": "home"},
+					{"path": "/shop", "layout": "shop", "children": [{"path": "x", "component": "home"}]}
+				]
+			}`)},
+			"index.html": {Data: []byte(test.layout)},
+			"home.html":  {Data: []byte(`home`)},
+			"base.html":  {Data: []byte(`{{template "layout-outlet" .}}`)},
+			"shop.html":  {Data: []byte(`{{template "router-outlet" .}}`)},
+		}
+		p, err := New(&Options{FS: fsys, JsonFilePath: "pages.json"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = p.BuildRouter()
+		if len(test.err) == 0 && err != nil {
+			t.Errorf("layout %s, outlets %s: %v", test.layout, test.outlets, err)
+		}
+		if len(test.err) > 0 && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("layout %s, outlets %s: got %v, want %s", test.layout, test.outlets, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package pages
+
+// routeLevels returns the routes of a path from the outermost parent down,
+// each once, up to the first redirect.
+func routeLevels(routes []*Route) []*Route {
+	var levels []*Route
+	var done = map[int]bool{}
+	for _, route := range routes {
+		if done[route.id] {
+			continue
+		}
+		done[route.id] = true
+		if len(route.Redirect) > 0 {
+			break
+		}
+		levels = append(levels, route)
+	}
+	return levels
+}
+
+// mergePage returns page with child merged into it. Nested objects are
+// merged key by key, any other child value replaces the parent's. Neither
+// map is modified.
+func mergePage(page, child map[string]interface{}) map[string]interface{} {
+	if page == nil {
+		return child
+	}
+	if child == nil {
+		return page
+	}
+	merged := make(map[string]interface{}, len(page)+len(child))
+	for k, v := range page {
+		merged[k] = v
+	}
+	for k, v := range child {
+		parent, ok := merged[k].(map[string]interface{})
+		if m, isMap := v.(map[string]interface{}); ok && isMap {
+			v = mergePage(parent, m)
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// levelContext exposes each route level to templates as levels.[n] with its
+// path, own page and the results of its own requests, so a parent route
+// keeps its data when a child renders.
+func levelContext(levels []*Route, results [][]interface{}) []interface{} {
+	context := make([]interface{}, len(levels))
+	for i, route := range levels {
+		level := map[string]interface{}{
+			"path": route.Path,
+			"page": route.Page,
+		}
+		if i < len(results) && results[i] != nil {
+			level["data"] = results[i]
+		}
+		context[i] = level
+	}
+	return context
+}
@@ -0,0 +1,121 @@
+package pages
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"time"
+)
+
+// ManifestLoader provides the route manifest to New and Reload. Paths in
+// the manifest are still resolved against the directory of
+// Options.JsonFilePath, in Options.FS when it is set.
+type ManifestLoader interface {
+	LoadManifest() (*Manifest, error)
+}
+
+// FileLoader reads the manifest from a file on disk.
+type FileLoader struct {
+	Path string
+}
+
+func (l FileLoader) LoadManifest() (*Manifest, error) {
+	return FSLoader{FS: osFS{}, Path: l.Path}.LoadManifest()
+}
+
+// FSLoader reads the manifest from a file in FS.
+type FSLoader struct {
+	FS   fs.FS
+	Path string
+}
+
+func (l FSLoader) LoadManifest() (*Manifest, error) {
+	m := new(Manifest)
+	err := readAndUnmarshal(l.FS, l.Path, m)
+	return m, err
+}
+
+// HTTPLoader fetches the manifest from a config service. Header is sent with
+// the request, Client defaults to one with a 10 second timeout.
+type HTTPLoader struct {
+	URL    string
+	Header http.Header
+	Client *http.Client
+}
+
+func (l HTTPLoader) LoadManifest() (*Manifest, error) {
+	client := l.Client
+	if client == nil {
+		client = &http.Client{Timeout: time.Second * 10}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, l.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range l.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("manifest " + l.URL + ": " + resp.Status)
+	}
+
+	m := new(Manifest)
+	err = json.NewDecoder(resp.Body).Decode(m)
+	return m, err
+}
+
+// loader is Options.Loader or the JsonFilePath manifest.
+func (p *Pages) loader() ManifestLoader {
+	if p.Loader != nil {
+		return p.Loader
+	}
+	return FSLoader{FS: p.fsys(), Path: p.JsonFilePath}
+}
+
+// Reload loads the manifest again and builds a new instance with the same
+// options, resolvers and helpers. When everything was built without errors
+// its routes replace the ones p serves atomically, through ServeHTTP and the
+// router BuildRouter returned; requests in progress finish on the previous
+// routes. p's fields keep describing the manifest p was created with, use the
+// returned instance for Manifest, Components and TranslationReport.
+func (p *Pages) Reload() (*Pages, error) {
+	p.reloadMu.Lock()
+	defer p.reloadMu.Unlock()
+
+	next, err := New(p.Options)
+	if err != nil {
+		return nil, err
+	}
+	for name, fn := range p.resolvers {
+		next.resolvers[name] = fn
+	}
+	for name, helper := range p.registered {
+		next.registered[name] = helper
+	}
+
+	if _, err := next.BuildRouter(); err != nil {
+		return nil, err
+	}
+	p.handler.Store(http.Handler(next.router))
+	return next, nil
+}
+
+// ServeHTTP serves the router built by BuildRouter or the last Reload.
+func (p *Pages) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h, ok := p.handler.Load().(http.Handler)
+	if !ok {
+		http.Error(w, "router not built", http.StatusServiceUnavailable)
+		return
+	}
+	h.ServeHTTP(w, req)
+}
@@ -0,0 +1,315 @@
+package pages
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ales6164/raymond"
+)
+
+// localeFormat holds the number, date and plural conventions of a language.
+type localeFormat struct {
+	decimal   string
+	group     string
+	currency  string // pattern with # for the number and ¤ for the symbol
+	percent   string // pattern with # for the number
+	shortDate string // Go layout
+	longDate  string // Go layout, English month names are translated
+	months    []string
+	plural    func(i int64, v int) string // CLDR plural category of integer part i with v fraction digits
+	relative  map[string][2]string        // unit to past and future message with {n}
+}
+
+const nbsp = "\u00a0"
+
+var localePrefix = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
+var localeFormats = map[string]*localeFormat{
+	"en": {
+		decimal:   ".",
+		group:     ",",
+		currency:  "¤#",
+		percent:   "#%",
+		shortDate: "1/2/2006",
+		longDate:  "January 2, 2006",
+		plural: func(i int64, v int) string {
+			if i == 1 && v == 0 {
+				return "one"
+			}
+			return "other"
+		},
+		relative: map[string][2]string{
+			"second": {"{n, plural, one {# second} other {# seconds}} ago", "in {n, plural, one {# second} other {# seconds}}"},
+			"minute": {"{n, plural, one {# minute} other {# minutes}} ago", "in {n, plural, one {# minute} other {# minutes}}"},
+			"hour":   {"{n, plural, one {# hour} other {# hours}} ago", "in {n, plural, one {# hour} other {# hours}}"},
+			"day":    {"{n, plural, one {# day} other {# days}} ago", "in {n, plural, one {# day} other {# days}}"},
+			"month":  {"{n, plural, one {# month} other {# months}} ago", "in {n, plural, one {# month} other {# months}}"},
+			"year":   {"{n, plural, one {# year} other {# years}} ago", "in {n, plural, one {# year} other {# years}}"},
+		},
+	},
+	"sl": {
+		decimal:   ",",
+		group:     ".",
+		currency:  "#" + nbsp + "¤",
+		percent:   "#" + nbsp + "%",
+		shortDate: "2. 1. 2006",
+		longDate:  "2. January 2006",
+		months:    []string{"januar", "februar", "marec", "april", "maj", "junij", "julij", "avgust", "september", "oktober", "november", "december"},
+		plural: func(i int64, v int) string {
+			switch {
+			case v == 0 && i%100 == 1:
+				return "one"
+			case v == 0 && i%100 == 2:
+				return "two"
+			case v == 0 && (i%100 == 3 || i%100 == 4), v != 0:
+				return "few"
+			}
+			return "other"
+		},
+		relative: map[string][2]string{
+			"second": {"pred {n, plural, one {# sekundo} two {# sekundama} other {# sekundami}}", "čez {n, plural, one {# sekundo} two {# sekundi} few {# sekunde} other {# sekund}}"},
+			"minute": {"pred {n, plural, one {# minuto} two {# minutama} other {# minutami}}", "čez {n, plural, one {# minuto} two {# minuti} few {# minute} other {# minut}}"},
+			"hour":   {"pred {n, plural, one {# uro} two {# urama} other {# urami}}", "čez {n, plural, one {# uro} two {# uri} few {# ure} other {# ur}}"},
+			"day":    {"pred {n, plural, one {# dnem} two {# dnevoma} other {# dnevi}}", "čez {n, plural, one {# dan} two {# dneva} other {# dni}}"},
+			"month":  {"pred {n, plural, one {# mesecem} two {# mesecema} other {# meseci}}", "čez {n, plural, one {# mesec} two {# meseca} few {# mesece} other {# mesecev}}"},
+			"year":   {"pred {n, plural, one {# letom} two {# letoma} other {# leti}}", "čez {n, plural, one {# leto} two {# leti} few {# leta} other {# let}}"},
+		},
+	},
+	"de": {
+		decimal:   ",",
+		group:     ".",
+		currency:  "#" + nbsp + "¤",
+		percent:   "#" + nbsp + "%",
+		shortDate: "02.01.2006",
+		longDate:  "2. January 2006",
+		months:    []string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		plural: func(i int64, v int) string {
+			if i == 1 && v == 0 {
+				return "one"
+			}
+			return "other"
+		},
+		relative: map[string][2]string{
+			"second": {"vor {n, plural, one {# Sekunde} other {# Sekunden}}", "in {n, plural, one {# Sekunde} other {# Sekunden}}"},
+			"minute": {"vor {n, plural, one {# Minute} other {# Minuten}}", "in {n, plural, one {# Minute} other {# Minuten}}"},
+			"hour":   {"vor {n, plural, one {# Stunde} other {# Stunden}}", "in {n, plural, one {# Stunde} other {# Stunden}}"},
+			"day":    {"vor {n, plural, one {# Tag} other {# Tagen}}", "in {n, plural, one {# Tag} other {# Tagen}}"},
+			"month":  {"vor {n, plural, one {# Monat} other {# Monaten}}", "in {n, plural, one {# Monat} other {# Monaten}}"},
+			"year":   {"vor {n, plural, one {# Jahr} other {# Jahren}}", "in {n, plural, one {# Jahr} other {# Jahren}}"},
+		},
+	},
+}
+
+var currencySymbols = map[string]string{
+	"EUR": "€",
+	"USD": "$",
+	"GBP": "£",
+	"JPY": "¥",
+	"CHF": "CHF",
+}
+
+// formatFor returns the conventions of locale, trying sl-SI, then sl and
+// falling back to English.
+func formatFor(locale string) *localeFormat {
+	locale = strings.ToLower(strings.Replace(locale, "_", "-", -1))
+	if f, ok := localeFormats[locale]; ok {
+		return f
+	}
+	if i := strings.IndexByte(locale, '-'); i > 0 {
+		if f, ok := localeFormats[locale[:i]]; ok {
+			return f
+		}
+	}
+	return localeFormats["en"]
+}
+
+// pluralCategory returns the CLDR plural category of n in locale.
+func pluralCategory(n float64, locale string) string {
+	s := strconv.FormatFloat(math.Abs(n), 'f', -1, 64)
+	v := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		v = len(s) - i - 1
+	}
+	return formatFor(locale).plural(int64(math.Abs(n)), v)
+}
+
+// formatNumber formats v with locale separators. A negative decimals keeps up
+// to three fraction digits.
+func formatNumber(v interface{}, decimals int, locale string) string {
+	n, ok := toFloat(v)
+	if !ok {
+		n, _ = strconv.ParseFloat(raymond.Str(v), 64)
+	}
+	f := formatFor(locale)
+
+	var s string
+	if decimals < 0 {
+		s = strconv.FormatFloat(n, 'f', 3, 64)
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	} else {
+		s = strconv.FormatFloat(n, 'f', decimals, 64)
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i+1:]
+	}
+
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(f.group)
+		}
+		b.WriteRune(c)
+	}
+	if len(frac) > 0 {
+		b.WriteString(f.decimal)
+		b.WriteString(frac)
+	}
+	return sign + b.String()
+}
+
+func formatCurrency(v interface{}, currency string, locale string) string {
+	symbol, ok := currencySymbols[strings.ToUpper(currency)]
+	if !ok {
+		symbol = strings.ToUpper(currency)
+	}
+	decimals := 2
+	if strings.ToUpper(currency) == "JPY" {
+		decimals = 0
+	}
+	n := formatNumber(v, decimals, locale)
+	sign := ""
+	if strings.HasPrefix(n, "-") {
+		sign, n = "-", n[1:]
+	}
+	return sign + strings.Replace(strings.Replace(formatFor(locale).currency, "#", n, 1), "¤", symbol, 1)
+}
+
+func formatPercent(v interface{}, decimals int, locale string) string {
+	n, _ := toFloat(v)
+	if decimals < 0 {
+		decimals = 0
+	}
+	return strings.Replace(formatFor(locale).percent, "#", formatNumber(n*100, decimals, locale), 1)
+}
+
+// formatLocalDate formats a date in the "short" (default) or "long" style of
+// locale. Any other style is used as a Go layout.
+func formatLocalDate(t time.Time, style string, locale string) string {
+	f := formatFor(locale)
+	switch style {
+	case "", "short":
+		return t.Format(f.shortDate)
+	case "long":
+		s := t.Format(f.longDate)
+		if f.months != nil {
+			s = strings.Replace(s, t.Month().String(), f.months[t.Month()-1], 1)
+		}
+		return s
+	}
+	return t.Format(dateLayout(style))
+}
+
+// formatRelativeTime describes t relative to now, such as "3 days ago".
+func formatRelativeTime(t time.Time, now time.Time, locale string) string {
+	d := t.Sub(now)
+	future := d > 0
+	if d < 0 {
+		d = -d
+	}
+
+	unit, n := "second", d.Seconds()
+	switch {
+	case d >= 365*24*time.Hour:
+		unit, n = "year", d.Hours()/(365*24)
+	case d >= 30*24*time.Hour:
+		unit, n = "month", d.Hours()/(30*24)
+	case d >= 24*time.Hour:
+		unit, n = "day", d.Hours()/24
+	case d >= time.Hour:
+		unit, n = "hour", d.Hours()
+	case d >= time.Minute:
+		unit, n = "minute", d.Minutes()
+	}
+
+	f := formatFor(locale)
+	msg := f.relative[unit][0]
+	if future {
+		msg = f.relative[unit][1]
+	}
+	s, err := formatMessage(msg, map[string]interface{}{"n": math.Floor(n)}, locale)
+	if err != nil {
+		return t.Format(f.shortDate)
+	}
+	return s
+}
+
+// requestLocale is the locale of the prefix of an escaped request path, as in
+// /sl/... or /sl-SI/..., or the manifest default locale.
+func (p *Pages) requestLocale(escapedPath string) string {
+	if locale, _, ok := pathLocale(escapedPath); ok {
+		return locale
+	}
+	return p.DefaultLocale
+}
+
+// pathLocale splits the locale prefix off an escaped path, returning the
+// locale and the rest of the path. ok is false when there is no prefix.
+func pathLocale(escapedPath string) (locale, rest string, ok bool) {
+	splitPath := strings.Split(escapedPath, "/")
+	if len(splitPath) > 1 && localePrefix.MatchString(splitPath[1]) {
+		return splitPath[1], "/" + strings.Join(splitPath[2:], "/"), true
+	}
+	return "", escapedPath, false
+}
+
+// localeOf returns the locale a template is rendered in.
+func (p *Pages) localeOf(options *raymond.Options) string {
+	if locale := options.DataStr("locale"); len(locale) > 0 {
+		return locale
+	}
+	return p.DefaultLocale
+}
+
+// localeHelpers format values according to the locale of the request.
+func (p *Pages) localeHelpers() map[string]interface{} {
+	decimals := func(options *raymond.Options) int {
+		if d := options.HashProp("decimals"); d != nil {
+			return toInt(d)
+		}
+		return -1
+	}
+	return map[string]interface{}{
+		"formatNumber": func(v interface{}, options *raymond.Options) string {
+			return formatNumber(v, decimals(options), p.localeOf(options))
+		},
+		"formatCurrency": func(v interface{}, currency string, options *raymond.Options) string {
+			return formatCurrency(v, currency, p.localeOf(options))
+		},
+		"formatPercent": func(v interface{}, options *raymond.Options) string {
+			return formatPercent(v, decimals(options), p.localeOf(options))
+		},
+		"formatLocalDate": func(v interface{}, options *raymond.Options) string {
+			t, ok := parseTime(v, "")
+			if !ok {
+				return ""
+			}
+			return formatLocalDate(t, options.HashStr("style"), p.localeOf(options))
+		},
+		"relativeTime": func(v interface{}, options *raymond.Options) string {
+			t, ok := parseTime(v, "")
+			if !ok {
+				return ""
+			}
+			return formatRelativeTime(t, time.Now(), p.localeOf(options))
+		},
+	}
+}
@@ -11,22 +11,40 @@ type Manifest struct {
 	Routes            []*Route        `json:"routes"`
 	Resources         json.RawMessage `json:"resources"`
 	parsedResources   interface{}
-	ComponentsVersion string `json:"componentsVersion"`
+	ComponentsVersion string            `json:"componentsVersion"`
+	Translations      map[string]string `json:"translations"` // locale to a .po, .xlf or .json translation file
+	Assets            []*AssetDir       `json:"assets"`       // static file directories, see the asset helper
+	Engine            string            `json:"engine"`       // template engine of imports without one, handlebars by default
+	Fragments         []string          `json:"fragments"`    // components routes with fragment set may render alone
 }
 
 type Import struct {
-	TemplatePath  string `json:"templatePath"`
-	ComponentPath string `json:"componentPath"`
-	Name          string `json:"name"`
-	IsLayout      bool   `json:"layout"`
-	Render        bool   `json:"render"`
-	OmitTags      bool   `json:"omitTags"`
+	TemplatePath  string            `json:"templatePath"`
+	ComponentPath string            `json:"componentPath"` // component script, bundled with the pages using it
+	StylePath     string            `json:"stylePath"`     // component style, bundled with the pages using it
+	Name          string            `json:"name"`
+	Glob          string            `json:"glob"`   // imports every matching template, named after its file
+	Prefix        string            `json:"prefix"` // prepended to names derived from file names
+	IsLayout      bool              `json:"layout"`
+	Render        bool              `json:"render"`
+	OmitTags      bool              `json:"omitTags"`
+	Engine        string            `json:"engine"`       // handlebars, html or one of Options.Engines
+	Outlets       map[string]string `json:"outlets"`      // outlets of a layout to the component shown when no route fills them, empty for none
+	Parent        string            `json:"parent"`       // layout this layout renders into
+	ParentOutlet  string            `json:"parentOutlet"` // outlet of Parent, DefaultLayoutOutlet by default; must differ for every layout of a chain
 }
 
 func (m *Manifest) GetResource(keys ...string) (string, error) {
 	return jsonparser.GetString(m.Resources, keys...)
 }
 
+const (
+	RequestGraphQL = "graphql"
+
+	OnErrorFail   = "fail"
+	OnErrorIgnore = "ignore"
+)
+
 /*
 path is a string that uses the route matcher DSL.
 pathMatch is a string that specifies the matching strategy.
@@ -50,22 +68,39 @@ type Route struct {
 	Component string                 `json:"component"`
 	Layout    string                 `json:"layout"`
 	Requests  []Request              `json:"requests"`
+	Resolve   []string               `json:"resolve"` // names of resolvers registered with Pages.RegisterResolver
 	Outlet    string                 `json:"outlet"`
+	Outlets   map[string]string      `json:"outlets"` // outlet to component, for routes filling several outlets
 	Children  []*Route               `json:"children"`
 	Page      map[string]interface{} `json:"page"`
 	Redirect  string                 `json:"redirect"`
 	Cache     bool                   `json:"cache"`
+	Hydrate   []string               `json:"hydrate"`  // context keys the hydrate helper writes; Page keys, query, locale and data when unset
+	Fragment  bool                   `json:"fragment"` // also serve the component alone under FragmentPath, see Manifest.Fragments
 
 	CanActivate      interface{} `json:"canActivate"`      // not implemented
 	CanActivateChild interface{} `json:"canActivateChild"` // not implemented
 	PathMatch        interface{} `json:"pathMatch"`        // not implemented
 
-	parents []*Route
+	parents  []*Route
+	fullPath string // path including the paths of parents, set by iter
 }
 
 type Request struct {
-	URL     string            `json:"url"`
-	Method  string            `json:"method"`
-	Headers map[string]string `json:"headers"`
-	Body    json.RawMessage   `json:"body"`
+	URL      string            `json:"url"`
+	Method   string            `json:"method"`
+	Headers  map[string]string `json:"headers"`
+	Body     json.RawMessage   `json:"body"`
+	Type     string            `json:"type"`     // "" for plain HTTP or "graphql"
+	OnError  string            `json:"onError"`  // "fail" (default) or "ignore" to render with null data
+	Format   string            `json:"format"`   // "json" (default), "xml", "text" or "html"
+	MaxBytes int64             `json:"maxBytes"` // response size limit, Options.MaxUpstreamBytes when 0
+
+	// graphql requests
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"` // "$name" values are bound from mux vars and query params, "$name:int", float or bool convert them
+
+	resolver string // set for route resolvers, see withResolvers
+	level    int    // index of the route in routeLevels the request belongs to
 }
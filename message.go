@@ -0,0 +1,169 @@
+package pages
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/ales6164/raymond"
+)
+
+var errMessageSyntax = errors.New("invalid message syntax")
+
+// formatMessage formats an ICU MessageFormat style message. It supports
+// {name} interpolation, {name, plural, ...} with =N and CLDR category
+// selectors where # is replaced by the formatted number, and
+// {name, select, ...}. A single quote before a syntax character starts
+// literal text, as in ICU.
+func formatMessage(msg string, args map[string]interface{}, locale string) (string, error) {
+	m := &messageParser{src: msg, args: args, locale: locale}
+	out, err := m.parse(false, nil)
+	if err == nil && m.pos < len(m.src) {
+		err = errMessageSyntax
+	}
+	return out, err
+}
+
+type messageParser struct {
+	src    string
+	pos    int
+	args   map[string]interface{}
+	locale string
+}
+
+// parse formats text up to the end of the message, or up to the closing brace
+// of the current plural or select branch when nested is set. hash is the
+// number # stands for inside plural branches.
+func (m *messageParser) parse(nested bool, hash interface{}) (string, error) {
+	var b strings.Builder
+	for m.pos < len(m.src) {
+		c := m.src[m.pos]
+		switch {
+		case c == '\'' && m.pos+1 < len(m.src) && strings.IndexByte("{}#'", m.src[m.pos+1]) >= 0:
+			// '' is a literal quote and a quote before a syntax character
+			// starts literal text up to the next single quote
+			if m.src[m.pos+1] == '\'' {
+				b.WriteByte('\'')
+				m.pos += 2
+				break
+			}
+			end := strings.IndexByte(m.src[m.pos+1:], '\'')
+			if end < 0 {
+				b.WriteString(m.src[m.pos+1:])
+				m.pos = len(m.src)
+			} else {
+				b.WriteString(m.src[m.pos+1 : m.pos+1+end])
+				m.pos += end + 2
+			}
+		case c == '#' && hash != nil:
+			b.WriteString(formatNumber(hash, -1, m.locale))
+			m.pos++
+		case c == '{':
+			m.pos++
+			s, err := m.argument(hash)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(s)
+		case c == '}':
+			if !nested {
+				return "", errMessageSyntax
+			}
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			m.pos++
+		}
+	}
+	if nested {
+		return "", errMessageSyntax
+	}
+	return b.String(), nil
+}
+
+// argument formats {name}, {name, plural, ...} or {name, select, ...}; the
+// opening brace has already been consumed. hash is the number of the
+// enclosing plural, kept for # in select branches.
+func (m *messageParser) argument(hash interface{}) (string, error) {
+	name := m.word()
+	value := m.args[name]
+	m.space()
+	if m.pos >= len(m.src) {
+		return "", errMessageSyntax
+	}
+	if m.src[m.pos] == '}' {
+		m.pos++
+		if f, ok := toFloat(value); ok {
+			return formatNumber(f, -1, m.locale), nil
+		}
+		return raymond.Str(value), nil
+	}
+	if m.src[m.pos] != ',' {
+		return "", errMessageSyntax
+	}
+	m.pos++
+	m.space()
+	kind := m.word()
+	m.space()
+	if m.pos >= len(m.src) || m.src[m.pos] != ',' {
+		return "", errMessageSyntax
+	}
+	m.pos++
+
+	var keys []string
+	switch kind {
+	case "plural":
+		n, _ := toFloat(value)
+		keys = []string{"=" + strconv.FormatFloat(n, 'f', -1, 64), pluralCategory(n, m.locale), "other"}
+		hash = n
+	case "select":
+		keys = []string{raymond.Str(value), "other"}
+	default:
+		return "", errMessageSyntax
+	}
+
+	// read all branches, keeping the best match
+	var result string
+	best := len(keys)
+	for {
+		m.space()
+		if m.pos >= len(m.src) {
+			return "", errMessageSyntax
+		}
+		if m.src[m.pos] == '}' {
+			m.pos++
+			return result, nil
+		}
+		selector := m.word()
+		m.space()
+		if m.pos >= len(m.src) || m.src[m.pos] != '{' || len(selector) == 0 {
+			return "", errMessageSyntax
+		}
+		m.pos++
+		branch, err := m.parse(true, hash)
+		if err != nil {
+			return "", err
+		}
+		m.pos++ // closing brace of the branch
+		for i, k := range keys[:best] {
+			if k == selector {
+				result, best = branch, i
+				break
+			}
+		}
+	}
+}
+
+func (m *messageParser) word() string {
+	start := m.pos
+	for m.pos < len(m.src) && !strings.ContainsRune(" \t\n,{}", rune(m.src[m.pos])) {
+		m.pos++
+	}
+	return m.src[start:m.pos]
+}
+
+func (m *messageParser) space() {
+	for m.pos < len(m.src) && strings.ContainsRune(" \t\n", rune(m.src[m.pos])) {
+		m.pos++
+	}
+}
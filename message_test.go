@@ -0,0 +1,77 @@
+package pages
+
+import "testing"
+
+func TestFormatMessage(t *testing.T) {
+	items := "{n, plural, =0 {no items} one {# item} other {# items}}"
+	sl := "{n, plural, one {# izdelek} two {# izdelka} few {# izdelki} other {# izdelkov}}"
+	tests := []struct {
+		msg    string
+		args   map[string]interface{}
+		locale string
+		want   string
+	}{
+		{"Hello {name}", map[string]interface{}{"name": "Ana"}, "en", "Hello Ana"},
+		{"{n} views", map[string]interface{}{"n": 12345.5}, "en", "12,345.5 views"},
+		{"{n} views", map[string]interface{}{"n": 12345.5}, "sl", "12.345,5 views"},
+
+		// =N before categories
+		{items, map[string]interface{}{"n": 0}, "en", "no items"},
+		{items, map[string]interface{}{"n": 1}, "en", "1 item"},
+		{items, map[string]interface{}{"n": 2}, "en", "2 items"},
+		{items, map[string]interface{}{"n": 1.5}, "en", "1.5 items"},
+		{"{n, plural, =1 {exactly one} one {# one} other {#}}", map[string]interface{}{"n": 1}, "en", "exactly one"},
+
+		// CLDR categories for sl
+		{sl, map[string]interface{}{"n": 1}, "sl", "1 izdelek"},
+		{sl, map[string]interface{}{"n": 101}, "sl", "101 izdelek"},
+		{sl, map[string]interface{}{"n": 2}, "sl", "2 izdelka"},
+		{sl, map[string]interface{}{"n": 102}, "sl", "102 izdelka"},
+		{sl, map[string]interface{}{"n": 3}, "sl", "3 izdelki"},
+		{sl, map[string]interface{}{"n": 4}, "sl", "4 izdelki"},
+		{sl, map[string]interface{}{"n": 5}, "sl", "5 izdelkov"},
+		{sl, map[string]interface{}{"n": 11}, "sl", "11 izdelkov"},
+		{sl, map[string]interface{}{"n": 1.5}, "sl", "1,5 izdelki"},
+		{sl, map[string]interface{}{"n": 1}, "sl-SI", "1 izdelek"},
+
+		// select and nesting
+		{"{g, select, f {She} m {He} other {They}} left", map[string]interface{}{"g": "f"}, "en", "She left"},
+		{"{g, select, f {She} m {He} other {They}} left", map[string]interface{}{"g": "x"}, "en", "They left"},
+		{"{n, plural, one {{g, select, f {# friend, hers} other {# friend}}} other {{g, select, f {# friends, hers} other {# friends}}}}",
+			map[string]interface{}{"n": 3, "g": "f"}, "en", "3 friends, hers"},
+		{"{g, select, other {{n, plural, one {# cat} other {# cats}}}}", map[string]interface{}{"n": 1, "g": "x"}, "en", "1 cat"},
+		{"{n, plural, other {{m, plural, one {# of #} other {# of them}}}}", map[string]interface{}{"n": 5, "m": 1}, "en", "1 of 1"},
+		{"{g, select, other {# stays}}", map[string]interface{}{"g": "x"}, "en", "# stays"},
+
+		// quoting
+		{"Don't '{'panic'}'", nil, "en", "Don't {panic}"},
+		{"It''s '{name}' and {name}", map[string]interface{}{"name": "x"}, "en", "It's {name} and x"},
+		{"{n, plural, other {'#' is #}}", map[string]interface{}{"n": 7}, "en", "# is 7"},
+		{"'{unclosed", nil, "en", "{unclosed"},
+	}
+	for _, test := range tests {
+		got, err := formatMessage(test.msg, test.args, test.locale)
+		if err != nil {
+			t.Errorf("formatMessage(%q, %s): %v", test.msg, test.locale, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("formatMessage(%q, %v, %s) = %q, want %q", test.msg, test.args, test.locale, got, test.want)
+		}
+	}
+}
+
+func TestFormatMessageSyntax(t *testing.T) {
+	for _, msg := range []string{
+		"{name",
+		"name}",
+		"{n, plural, one {x}",
+		"{n, plural, one x}",
+		"{n, unknown, other {x}}",
+		"{n plural}",
+	} {
+		if _, err := formatMessage(msg, nil, "en"); err == nil {
+			t.Errorf("formatMessage(%q): no error", msg)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package pages
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pathVar matches the variables of a route path, such as {id} or {id:[0-9]+}.
+var pathVar = regexp.MustCompile(`\{(\w+)(?::[^}]*)?\}`)
+
+// routeTitle is the title in a route's Page.
+func routeTitle(route *Route) string {
+	title, _ := route.Page["title"].(string)
+	return title
+}
+
+// routeURL returns the path of route with vars filled in, or an empty string
+// when a variable has no value.
+func routeURL(route *Route, vars map[string]string) string {
+	var missing bool
+	url := pathVar.ReplaceAllStringFunc(strings.TrimSuffix(route.fullPath, "*"), func(s string) string {
+		v, ok := vars[pathVar.FindStringSubmatch(s)[1]]
+		if !ok || len(v) == 0 {
+			missing = true
+		}
+		return v
+	})
+	if missing {
+		return ""
+	}
+	return url
+}
+
+// breadcrumbs lists the parents of route and route itself that have a title,
+// outermost first, each with its title and url.
+func breadcrumbs(route *Route, vars map[string]string) []interface{} {
+	var crumbs = []interface{}{}
+	for _, r := range append(append([]*Route{}, route.parents...), route) {
+		if title := routeTitle(r); len(title) > 0 {
+			crumbs = append(crumbs, map[string]interface{}{
+				"title": title,
+				"url":   routeURL(r, vars),
+			})
+		}
+	}
+	return crumbs
+}
+
+// navTree returns the titled routes as nested nodes with title, url, active
+// and children. Children of untitled routes move up to the nearest titled
+// ancestor. Routes in active are the rendered route and its parents.
+func navTree(routes []*Route, active map[int]bool, vars map[string]string) []interface{} {
+	var nodes = []interface{}{}
+	for _, route := range routes {
+		children := navTree(route.Children, active, vars)
+		title := routeTitle(route)
+		if len(title) == 0 {
+			nodes = append(nodes, children...)
+			continue
+		}
+		nodes = append(nodes, map[string]interface{}{
+			"title":    title,
+			"url":      routeURL(route, vars),
+			"active":   active[route.id],
+			"children": children,
+		})
+	}
+	return nodes
+}
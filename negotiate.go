@@ -0,0 +1,68 @@
+package pages
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FormatParam is the query parameter requesting a route's context as JSON
+// with the value json.
+const FormatParam = "_format"
+
+// pageJSON is the resolved context of a route for client side navigation.
+type pageJSON struct {
+	Route       string                 `json:"route"`
+	Layout      string                 `json:"layout"`
+	Components  []string               `json:"components"`
+	Page        map[string]interface{} `json:"page"`
+	Query       map[string]string      `json:"query"`
+	Data        interface{}            `json:"data"`
+	Levels      interface{}            `json:"levels"`
+	Breadcrumbs interface{}            `json:"breadcrumbs"`
+	Nav         interface{}            `json:"nav"`
+	Locale      string                 `json:"locale"`
+}
+
+// wantsJSON reports whether req asks for the route context instead of HTML,
+// with ?_format=json or an Accept header listing application/json but not
+// text/html.
+func wantsJSON(req *http.Request) bool {
+	if format := req.URL.Query().Get(FormatParam); len(format) > 0 {
+		return format == FormatJSON
+	}
+	var json bool
+	for _, part := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "application/json":
+			json = true
+		case "text/html":
+			return false
+		}
+	}
+	return json
+}
+
+// upstreamQuery returns the query parameters of req that are forwarded to
+// upstream requests, all but FormatParam.
+func upstreamQuery(req *http.Request) url.Values {
+	query := req.URL.Query()
+	query.Del(FormatParam)
+	return query
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_, _ = w.Write(b)
+}
@@ -1,41 +1,65 @@
 package pages
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"github.com/ales6164/raymond"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
+	"io/fs"
 	"log"
 	"net/http"
-	"net/url"
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
 type Pages struct {
-	router  *mux.Router
-	session *sessions.CookieStore
-	locale  string // current locale
+	base      string
+	router    *mux.Router
+	fragments *mux.Router  // routes under FragmentPath
+	handler   atomic.Value // http.Handler served by ServeHTTP, see Reload
+	served    *mux.Router  // returned by BuildRouter, serves handler
+	reloadMu  sync.Mutex
+	session   *sessions.CookieStore
+	client    *http.Client
+	flights   flightGroup       // in-flight upstream calls, see flightKey
+	fixtures  map[string]string // upstream URL to local file, see Options.FixturesPath
+	locale    string            // current locale
 	*Options
 	*Manifest
 	Components map[string]*Component
+	resolvers  map[string]Resolver
+	helpers    map[string]interface{} // template helpers of this instance
+	htmlFuncs  map[string]interface{} // helpers replacing the raymond ones in html templates
+	registered map[string]interface{} // helpers added with RegisterHelper
+	partials   map[string]string      // component partials of this instance
+	bundles    map[string]*bundle     // component script and style bundles by hash
+	assets     map[string]*asset      // static assets by name
+	assetURLs  map[string]*asset      // static assets by fingerprinted and plain URL
 	routeCount int
 }
 
 type Options struct {
-	base               string
-	IsRendering        bool
-	JsonFilePath       string
-	ForceSSL           bool
-	EnableSessionStore bool
-	SessionKey         []byte // key must be 16, 24 or 32 bytes long (AES-128, AES-192 or AES-256)
-	ForceHostname      string
-	forceHostname      bool
+	IsRendering         bool
+	JsonFilePath        string
+	ForceSSL            bool
+	EnableSessionStore  bool
+	SessionKey          []byte         // key must be 16, 24 or 32 bytes long (AES-128, AES-192 or AES-256)
+	FS                  fs.FS          // read the manifest and every file it references from FS instead of the OS
+	Loader              ManifestLoader // loads the manifest instead of reading JsonFilePath
+	ForceHostname       string
+	AllowedUpstreams    []Upstream                // hosts Requests may call; any public host when empty
+	AllowLocalUpstreams bool                      // allow Requests to loopback and link-local addresses
+	FixturesPath        string                    // JSON file mapping upstream URLs, or URL#operationName for GraphQL, to local files; disables network access
+	MaxUpstreamBytes    int64                     // response size limit for Requests, DefaultMaxUpstreamBytes when 0
+	Helpers             map[string]interface{}    // custom template helpers, see Pages.RegisterHelper
+	Engines             map[string]TemplateEngine // template engines selectable by name besides handlebars and html
+	StrictTranslations  bool                      // fail BuildRouter when a used translation key is missing, see TranslationReport
 }
 
 const (
@@ -52,7 +76,7 @@ func (p *Pages) withMiddleware(next http.Handler) http.Handler {
 				return
 			}
 		}
-		if p.forceHostname && r.Host != p.ForceHostname {
+		if len(p.ForceHostname) > 0 && r.Host != p.ForceHostname {
 			http.Redirect(w, r, "https://"+p.ForceHostname+r.RequestURI, http.StatusMovedPermanently)
 			return
 		}
@@ -65,7 +89,11 @@ func New(opt *Options) (*Pages, error) {
 		Options:    opt,
 		Manifest:   new(Manifest),
 		Components: map[string]*Component{},
+		resolvers:  map[string]Resolver{},
+		partials:   map[string]string{},
+		registered: map[string]interface{}{},
 	}
+	p.client = p.newClient()
 
 	if opt.EnableSessionStore {
 		if len(opt.SessionKey) == 0 || len(opt.SessionKey)%8 != 0 {
@@ -75,7 +103,23 @@ func New(opt *Options) (*Pages, error) {
 	}
 
 	// read manifest
-	err := readAndUnmarshal(p.JsonFilePath, p.Manifest)
+	manifest, err := p.loader().LoadManifest()
+	if err != nil {
+		return p, err
+	}
+	p.Manifest = manifest
+
+	if len(p.FixturesPath) > 0 {
+		err = p.loadFixtures()
+		if err != nil {
+			return p, err
+		}
+	}
+
+	// set base path from calling script absolute path and settings.json dir
+	p.base = filepath.Dir(p.JsonFilePath)
+
+	err = p.loadTranslations()
 	if err != nil {
 		return p, err
 	}
@@ -86,34 +130,100 @@ func New(opt *Options) (*Pages, error) {
 		return p, err
 	}
 
-	// set base path from calling script absolute path and settings.json dir
-	p.base = filepath.Dir(p.JsonFilePath)
-
 	// read partials
 	for _, imp := range p.Imports {
 		if len(imp.ComponentPath) > 0 {
-			if !filepath.IsAbs(imp.ComponentPath) {
-				imp.ComponentPath = filepath.Join(p.base, imp.ComponentPath)
-			}
+			imp.ComponentPath = p.resolve(imp.ComponentPath)
+		}
+		if len(imp.StylePath) > 0 {
+			imp.StylePath = p.resolve(imp.StylePath)
 		}
 		if len(imp.TemplatePath) > 0 {
 
 			// single file definition
-			if !filepath.IsAbs(imp.TemplatePath) {
-				imp.TemplatePath = filepath.Join(p.base, imp.TemplatePath)
+			imp.TemplatePath = p.resolve(imp.TemplatePath)
+			if len(imp.Name) == 0 {
+				imp.Name = importName(imp.Prefix, imp.TemplatePath)
 			}
 
-			newC, err := NewComponent(imp)
+			err = p.addComponent(imp)
 			if err != nil {
 				return p, err
 			}
-			p.Components[imp.Name] = newC
+		} else if len(imp.Glob) > 0 {
+
+			// directory of components
+			imp.Glob = p.resolve(imp.Glob)
+			files, err := fs.Glob(p.fsys(), imp.Glob)
+			if err != nil {
+				return p, err
+			}
+			sort.Strings(files)
+
+			for _, f := range files {
+				globImp := *imp
+				globImp.Glob = ""
+				globImp.ComponentPath = existing(p.fsys(), companion(f, ".js"))
+				globImp.StylePath = existing(p.fsys(), companion(f, ".css"))
+				globImp.TemplatePath = f
+				globImp.Name = importName(imp.Prefix, f)
+
+				err = p.addComponent(&globImp)
+				if err != nil {
+					return p, err
+				}
+			}
 		}
 	}
 
+	err = p.loadAssets()
+	if err != nil {
+		return p, err
+	}
+
 	return p, nil
 }
 
+func (p *Pages) addComponent(imp *Import) error {
+	if existing, ok := p.Components[imp.Name]; ok {
+		return errors.New("component " + imp.Name + " defined by both " + existing.TemplatePath + " and " + imp.TemplatePath)
+	}
+	if len(imp.Engine) == 0 {
+		imp.Engine = p.Manifest.Engine
+	}
+	if len(imp.Engine) == 0 {
+		imp.Engine = EngineHandlebars
+	}
+	engine, err := p.engine(imp.Engine)
+	if err != nil {
+		return err
+	}
+	newC, err := newComponent(p.fsys(), imp, engine)
+	if err != nil {
+		return err
+	}
+	p.Components[imp.Name] = newC
+	p.partials[imp.Name] = newC.partial
+	return nil
+}
+
+// companion returns the name of the file next to filePath with the same name
+// and the given extension.
+func companion(filePath, ext string) string {
+	return filePath[0:len(filePath)-len(filepath.Ext(filePath))] + ext
+}
+
+// importName derives a component name from its file name, as in v1:
+// prefix-name for templates/name.html.
+func importName(prefix, filePath string) string {
+	name := filepath.Base(filePath)
+	name = name[0 : len(name)-len(filepath.Ext(name))]
+	if len(prefix) > 0 {
+		name = prefix + "-" + name
+	}
+	return name
+}
+
 func (p *Pages) iter(h map[string][]*Route, route *Route, basePath string, parents []*Route) map[string][]*Route {
 	p.routeCount += 1
 
@@ -124,6 +234,7 @@ func (p *Pages) iter(h map[string][]*Route, route *Route, basePath string, paren
 	if len(route.Path) > 1 && route.Path[len(route.Path)-1:] == "/" {
 		newPath += "/"
 	}
+	route.fullPath = newPath
 
 	h[newPath] = append(h[newPath], parents...)
 
@@ -145,37 +256,28 @@ func (p *Pages) BuildRouter() (*mux.Router, error) {
 	p.router = mux.NewRouter()
 	p.routeCount = -1
 
-	// add json helper
-	raymond.RegisterHelper("stringify", func(k interface{}) string {
-		d, _ := json.Marshal(k)
-		return string(d)
-	})
+	err := p.buildHelpers()
+	if err != nil {
+		return p.router, err
+	}
 
-	// append string helper
-	raymond.RegisterHelper("append", func(k1, k2 string) string {
-		return k1 + k2
-	})
+	err = p.checkTranslations()
+	if err != nil {
+		return p.router, err
+	}
 
-	raymond.RegisterHelper("append3", func(k1, k2, k3 string) string {
-		return k1 + k2 + k3
-	})
+	err = p.checkAssets()
+	if err != nil {
+		return p.router, err
+	}
 
-	// add translation helper
-	raymond.RegisterHelper("trans", func(locale string, k string) string {
-		v, err := p.Manifest.GetResource("translations", locale, k)
-		if err != nil {
-			return k
-		}
-		return v
-	})
+	for _, prefix := range p.assetPrefixes() {
+		p.router.PathPrefix(prefix).Handler(p.withMiddleware(http.HandlerFunc(p.serveAsset)))
+	}
 
-	raymond.RegisterHelper("i18n", func(k string) string {
-		v, err := p.Manifest.GetResource("translations", p.Manifest.DefaultLocale, k)
-		if err != nil {
-			return k
-		}
-		return v
-	})
+	p.bundles = map[string]*bundle{}
+	p.router.Handle(BundlePath+"{version}/{file}", p.withMiddleware(http.HandlerFunc(p.serveBundle)))
+	p.fragments = p.router.PathPrefix(FragmentPath).Subrouter()
 
 	// attaches routes to paths - this way we don't have two Handlers for the same path
 	var handle = map[string][]*Route{}
@@ -190,43 +292,83 @@ func (p *Pages) BuildRouter() (*mux.Router, error) {
 		}
 	}
 
-	return p.router, nil
+	// the returned router serves through p so that it follows Reload
+	p.handler.Store(http.Handler(p.router))
+	if p.served == nil {
+		p.served = mux.NewRouter()
+		p.served.PathPrefix("/").Handler(p)
+	}
+	return p.served, nil
 }
 
 //var cachedPages = map[string][]byte{}
 
 // one path can have multiple routes defined -> when having multiple routers on one page
 func (p *Pages) handleRoute(r *mux.Router, path string, routes []*Route) (err error) {
-	var layout = DefaultLayout
+	var layout = routeLayout(routes)
 
-	if len(routes) > 0 {
-		if r := routes[0]; r != nil && len(r.Layout) > 0 {
-			layout = r.Layout
-		}
+	layoutComponent, ok := p.Components[layout]
+	if !ok {
+		return errors.New("layout " + layout + " doesn't exist")
 	}
 
-	routerPageVars, templ, requests, redirect, _, err := p.RenderRoute(p.Components[layout], routes)
+	routerPageVars, templ, requests, redirect, _, err := p.RenderRoute(layoutComponent, routes)
 	if err != nil {
 		return err
 	}
 
-	var hasApi = len(requests) > 0
+	for _, r := range requests {
+		if _, ok := p.resolvers[r.resolver]; len(r.resolver) > 0 && !ok {
+			return errors.New("resolver " + r.resolver + " doesn't exist")
+		}
+		if _, ok := contentTypes[r.format()]; !ok {
+			return errors.New("request format " + r.Format + " doesn't exist")
+		}
+	}
 
-	var handleFunc http.HandlerFunc
-	//var resolvedRedirectUri string
-	if len(redirect) > 0 {
-		handleFunc = func(w http.ResponseWriter, req *http.Request) {
-			// todo: doesnt work
-			/*vars := mux.Vars(req)
+	var hasApi = len(requests) > 0
+	var levels = routeLevels(routes)
+	var active = map[int]bool{}
+	for _, route := range levels {
+		active[route.id] = true
+	}
 
-			resolvedRedirectUri = regex.ReplaceAllStringFunc(redirect, func(s string) string {
-				return vars[s[1:]]
-			})*/
+	chain, err := p.layoutChain(layoutComponent)
+	if err != nil {
+		return err
+	}
+	// paths of routes with children only get the parents, see iter
+	rendered := len(routes) > 0 && routes[len(routes)-1].fullPath == path
+	if rendered && len(redirect) == 0 {
+		if l, outlet, ok := p.unfilledOutlet(chain, routes); ok {
+			return errors.New("route " + path + ": outlet " + outlet + " of layout " + l + " is not filled, add it to the layout's outlets")
+		}
+	}
 
-			http.Redirect(w, req, redirect, http.StatusPermanentRedirect)
+	// components rendered in outlets, without the layouts
+	var components = []string{}
+	for _, name := range outletComponents(chain, routes) {
+		if len(name) > 0 {
+			components = appendUnique(components, name)
 		}
-	} else {
-		handleFunc = func(w http.ResponseWriter, req *http.Request) {
+	}
+	sort.Strings(components)
+
+	used := []string{layout}
+	for _, l := range chain {
+		used = appendUnique(used, l.Name)
+	}
+	for _, name := range components {
+		used = appendUnique(used, name)
+	}
+	componentBundle := p.bundleFor(used)
+	hydrated := hydrateKeys(routes)
+	bundleVersion := p.bundleVersion()
+
+	// render runs the route's requests and executes templ, fragments are
+	// written without the component bundle.
+	render := func(templ Template, fragment bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
 			_ = req.Body.Close()
 
 			pageContext := map[string]interface{}{
@@ -256,102 +398,70 @@ func (p *Pages) handleRoute(r *mux.Router, path string, routes []*Route) (err er
 			}
 			pageContext["locale"] = locale*/
 
-			splitPath := strings.Split(req.URL.EscapedPath(), "/")
-
-			if len(splitPath) > 1 && len(splitPath[1]) == 2 {
-				// alternate url
-				pageContext["alternate"] = "/" + strings.Join(splitPath[2:], "/")
-			} else {
-				pageContext["alternate"] = strings.Join(splitPath, "/")
+			// the request path of the route, fragments without FragmentPath
+			routePath := req.URL.EscapedPath()
+			if fragment {
+				routePath = strings.TrimPrefix(routePath, FragmentPath)
 			}
 
+			// alternate url, the path without its locale prefix
+			_, pageContext["alternate"], _ = pathLocale(routePath)
+
+			locale := p.requestLocale(routePath)
+			pageContext["locale"] = locale
+
 			// add query parameters to the api request
+			var results = make([][]interface{}, len(levels))
 			if hasApi {
-				var dataArray = make([]interface{}, len(requests))
-
 				for index, r := range requests {
-					resolvedApiUri := regex.ReplaceAllStringFunc(r.URL, func(s string) string {
+					for _, s := range regex.FindAllString(r.URL+string(r.Body), -1) {
 						pageContext["query"].(map[string]string)[s[1:]] = vars[s[1:]]
-						return vars[s[1:]]
-					})
-
-					apiUrl, err := url.Parse(resolvedApiUri)
-					if err != nil {
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
-					}
-
-					apiUrlQuery := apiUrl.Query()
-					reqQuery := req.URL.Query()
-					for paramName, val := range reqQuery {
-						for _, v := range val {
-							apiUrlQuery.Add(paramName, v)
-						}
-					}
-					apiUrl.RawQuery = apiUrlQuery.Encode()
-
-					var client = &http.Client{
-						Timeout: time.Second * 10,
-					}
-
-					/*var reader io.Reader
-					if r.BodyReader != nil {
-						reader = r.BodyReader
-					}*/
-
-					var req *http.Request
-					var resp *http.Response
-					buf := new(bytes.Buffer)
-
-					if r.Body != nil {
-						newBody := regex.ReplaceAllStringFunc(string(r.Body), func(s string) string {
-							pageContext["query"].(map[string]string)[s[1:]] = vars[s[1:]]
-							return vars[s[1:]]
-						})
-
-						buf.WriteString(newBody)
-						req, err = http.NewRequest(r.Method, apiUrl.String(), buf)
-					} else {
-						req, err = http.NewRequest(r.Method, apiUrl.String(), nil)
 					}
 
+					data, err := p.fetch(r, req, vars)
 					if err != nil {
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
-					}
-
-					for key, value := range r.Headers {
-						req.Header.Add(key, value)
-					}
-
-					resp, err = client.Do(req)
-					buf.Reset()
-
-					if err != nil {
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
-					}
-					if resp.StatusCode != http.StatusOK {
-						http.Error(w, http.StatusText(resp.StatusCode), resp.StatusCode)
-						return
+						if r.OnError != OnErrorIgnore {
+							http.Error(w, err.Error(), statusCode(err))
+							return
+						}
+						log.Printf("Ignoring failed request %d on path %s: %s", index, path, err.Error())
 					}
+					results[r.level] = append(results[r.level], data)
+				}
 
-					_, _ = buf.ReadFrom(resp.Body)
-					_ = resp.Body.Close()
-
-					var data interface{}
-					err = json.Unmarshal(buf.Bytes(), &data)
-					if err != nil {
-						http.Error(w, err.Error(), http.StatusInternalServerError)
-						return
-					}
-					dataArray[index] = data
-					buf.Reset()
+				// data holds the results of the deepest route's requests
+				if leaf := results[len(results)-1]; leaf != nil {
+					pageContext["data"] = leaf
 				}
+			}
+			pageContext["levels"] = levelContext(levels, results)
 
-				pageContext["data"] = dataArray
+			// navigation from the manifest
+			if len(levels) > 0 {
+				pageContext["breadcrumbs"] = breadcrumbs(levels[len(levels)-1], vars)
+			}
+			pageContext["nav"] = navTree(p.Routes, active, vars)
+
+			w.Header().Add("Vary", "Accept")
+			if wantsJSON(req) {
+				writeJSON(w, pageJSON{
+					Route:       path,
+					Layout:      layout,
+					Components:  components,
+					Page:        routerPageVars,
+					Query:       vars,
+					Data:        pageContext["data"],
+					Levels:      pageContext["levels"],
+					Breadcrumbs: pageContext["breadcrumbs"],
+					Nav:         pageContext["nav"],
+					Locale:      locale,
+				})
+				return
 			}
 
+			// Deprecated: contextObject holds every key of the context,
+			// storage included, and isn't safe inside a script element.
+			// Templates should use the hydrate helper instead.
 			jsonContext, err := json.Marshal(pageContext)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -359,16 +469,51 @@ func (p *Pages) handleRoute(r *mux.Router, path string, routes []*Route) (err er
 			}
 			pageContext["contextObject"] = string(jsonContext)
 
-			html, err := templ.Exec(pageContext)
+			// the hydrate script is only built when a template uses it
+			hydrate := func() raymond.SafeString {
+				script, err := hydrateScript(pageContext, hydrated)
+				if err != nil {
+					log.Printf("Hydrating path %s: %s", path, err.Error())
+				}
+				return script
+			}
+
+			html, err := templ.Execute(pageContext, map[string]interface{}{
+				"locale":  locale,
+				"hydrate": hydrate,
+			})
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
+			if !fragment {
+				html = componentBundle.inject(html, bundleVersion)
+			}
 			_, _ = w.Write([]byte(html))
 		}
 	}
 
-	p.forceHostname = len(p.ForceHostname) > 0
+	var handleFunc http.HandlerFunc
+	//var resolvedRedirectUri string
+	if len(redirect) > 0 {
+		handleFunc = func(w http.ResponseWriter, req *http.Request) {
+			// todo: doesnt work
+			/*vars := mux.Vars(req)
+
+			resolvedRedirectUri = regex.ReplaceAllStringFunc(redirect, func(s string) string {
+				return vars[s[1:]]
+			})*/
+
+			http.Redirect(w, req, redirect, http.StatusPermanentRedirect)
+		}
+	} else {
+		handleFunc = render(templ, false)
+
+		err = p.handleFragment(path, routes, render)
+		if err != nil {
+			return err
+		}
+	}
 
 	if path[len(path)-1:] == "*" {
 		// catch all handler
@@ -391,8 +536,8 @@ var (
 	regex = regexp.MustCompile(`\$(\w+)`)
 )
 
-func (p *Pages) RenderRoute(layout *Component, routes []*Route) (map[string]interface{}, *raymond.Template, []Request, string, bool, error) {
-	var body = layout.Template.Clone()
+func (p *Pages) RenderRoute(layout *Component, routes []*Route) (map[string]interface{}, Template, []Request, string, bool, error) {
+	var outlets = map[string]string{}
 	var requests []Request
 	var redirect string
 	var done = map[int]bool{}
@@ -420,25 +565,129 @@ func (p *Pages) RenderRoute(layout *Component, routes []*Route) (map[string]inte
 			break
 		}
 
-		routePage = route.Page
+	}
+
+	// page data and requests are inherited from parent routes
+	for level, route := range routeLevels(routes) {
+		routePage = mergePage(routePage, route.Page)
+		for _, r := range withResolvers(route) {
+			r.level = level
+			requests = append(requests, r)
+		}
+	}
+
+	chain, err := p.layoutChain(layout)
+	if err != nil {
+		return routePage, nil, requests, redirect, cache, err
+	}
 
-		// set outlet
-		outlet := route.Outlet
-		if len(outlet) == 0 {
-			outlet = DefaultOutlet
+	for outlet, name := range outletComponents(chain, routes) {
+		if len(name) == 0 {
+			outlets[outlet] = ""
+			continue
+		}
+		component, ok := p.Components[name]
+		if !ok {
+			return routePage, nil, requests, redirect, cache, errors.New("component " + name + " doesn't exist")
 		}
+		if component.Engine != layout.Engine {
+			return routePage, nil, requests, redirect, cache, errors.New("component " + name + " uses engine " + component.Engine + ", layout " + layout.Name + " uses " + layout.Engine)
+		}
+		outlets[outlet] = layout.engine.Include(component.Name)
+	}
 
-		requests = route.Requests
+	// each layout is rendered in the outlet of its parent
+	for _, l := range chain[1:] {
+		outlets[l.parentOutlet()] = l.source
+	}
 
+	templ, err := p.template(chain[0], outlets)
+	return routePage, templ, requests, redirect, cache, err
+}
+
+// outletComponents maps the outlets of a layout chain to the component
+// rendered in them: the default of the innermost layout declaring the outlet
+// unless a route fills it with Component or Outlets. Deeper routes win. An
+// empty name renders nothing.
+func outletComponents(chain []*Component, routes []*Route) map[string]string {
+	var outlets = map[string]string{}
+	for _, layout := range chain {
+		for outlet, name := range layout.Outlets {
+			outlets[outlet] = name
+		}
+	}
+	for _, route := range routes {
+		if len(route.Redirect) > 0 {
+			break
+		}
 		if len(route.Component) > 0 {
-			if component, ok := p.Components[route.Component]; ok {
-				body.RegisterPartial(outlet, "{{> "+component.Name+"}}")
-			} else {
-				return route.Page, body, requests, redirect, cache, errors.New("component " + route.Component + " doesn't exist")
+			outlet := route.Outlet
+			if len(outlet) == 0 {
+				outlet = DefaultOutlet
 			}
+			outlets[outlet] = route.Component
+		}
+		for outlet, name := range route.Outlets {
+			outlets[outlet] = name
 		}
+	}
+	return outlets
+}
 
+// unfilledOutlet returns a partial a layout of chain includes that neither
+// routes, the layout chain nor a component fills. It would only fail when
+// rendered.
+func (p *Pages) unfilledOutlet(chain []*Component, routes []*Route) (layout, outlet string, ok bool) {
+	filled := outletComponents(chain, routes)
+	for _, l := range chain[1:] {
+		filled[l.parentOutlet()] = l.Name
+	}
+	for _, l := range chain {
+		if d, ok := l.engine.(definer); ok {
+			for _, name := range d.Defines(l.source) {
+				filled[name] = l.Name
+			}
+		}
+	}
+	for _, l := range chain {
+		for _, name := range l.includes {
+			_, isOutlet := filled[name]
+			_, isComponent := p.Components[name]
+			if !isOutlet && !isComponent {
+				return l.Name, name, true
+			}
+		}
 	}
+	return "", "", false
+}
 
-	return routePage, body, requests, redirect, cache, nil
+// template returns a copy of c's template with the helpers and the partials
+// of components using the same engine registered on it. Partials in
+// overrides, such as router outlets, take precedence over components of the
+// same name.
+func (p *Pages) template(c *Component, overrides map[string]string) (Template, error) {
+	partials := map[string]string{}
+	for name, source := range p.partials {
+		if p.Components[name].Engine == c.Engine {
+			partials[name] = source
+		}
+	}
+	for name, source := range overrides {
+		partials[name] = source
+	}
+	helpers := p.helpers
+	if c.Engine == EngineHTML {
+		helpers = make(map[string]interface{}, len(p.helpers)+len(p.htmlFuncs))
+		for name, helper := range p.helpers {
+			helpers[name] = helper
+		}
+		for name, helper := range p.htmlFuncs {
+			helpers[name] = helper
+		}
+	}
+	t, err := c.Template.With(helpers, partials)
+	if err != nil {
+		return nil, errors.New("component " + c.Name + ": " + err.Error())
+	}
+	return t, nil
 }
@@ -0,0 +1,134 @@
+package pages
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// statusError is returned when an upstream responds with anything but 200 OK.
+// The status is passed on to the client.
+type statusError struct {
+	status int
+}
+
+func (e statusError) Error() string {
+	return http.StatusText(e.status)
+}
+
+func statusCode(err error) int {
+	var se statusError
+	if errors.As(err, &se) {
+		return se.status
+	}
+	var ge graphQLError
+	if errors.As(err, &ge) {
+		return http.StatusBadGateway
+	}
+	if errors.Is(err, ErrUpstreamNotAllowed) || errors.Is(err, ErrLocalUpstream) ||
+		errors.Is(err, ErrResponseTooLarge) || errors.Is(err, ErrContentType) {
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// fetch resolves a single route Request for req and returns its decoded body.
+func (p *Pages) fetch(r Request, req *http.Request, vars map[string]string) (interface{}, error) {
+	if len(r.resolver) > 0 {
+		return p.resolvers[r.resolver](req.Context(), req, vars)
+	}
+
+	if strings.HasPrefix(r.URL, fileScheme) {
+		name, err := p.dataFile(r.URL, vars)
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Format) > 0 {
+			return readFile(p.fsys(), name, r.format())
+		}
+		return readData(p.fsys(), name)
+	}
+
+	if r.Type == RequestGraphQL {
+		return p.fetchGraphQL(r, req, vars)
+	}
+
+	apiUrl, err := url.Parse(resolveURL(r.URL, vars))
+	if err != nil {
+		return nil, err
+	}
+
+	apiUrlQuery := apiUrl.Query()
+	for paramName, val := range upstreamQuery(req) {
+		for _, v := range val {
+			apiUrlQuery.Add(paramName, v)
+		}
+	}
+	apiUrl.RawQuery = apiUrlQuery.Encode()
+
+	var body []byte
+	if r.Body != nil {
+		body = []byte(resolveBody(string(r.Body), vars))
+	}
+
+	return p.roundTrip(r, r.Method, apiUrl, body)
+}
+
+// roundTrip sends a resolved request upstream, or reads its fixture, and
+// decodes the response body.
+func (p *Pages) roundTrip(r Request, method string, apiUrl *url.URL, body []byte) (interface{}, error) {
+	if err := p.checkUpstream(apiUrl); err != nil {
+		return nil, err
+	}
+
+	if p.fixtures != nil {
+		return p.fixture(apiUrl, r)
+	}
+
+	return p.flights.do(flightKey(r, method, apiUrl.String(), body), func() (interface{}, error) {
+		return p.send(r, method, apiUrl, body)
+	})
+}
+
+// send makes the upstream call and decodes its response.
+func (p *Pages) send(r Request, method string, apiUrl *url.URL, body []byte) (interface{}, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	upstreamReq, err := http.NewRequest(method, apiUrl.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range r.Headers {
+		upstreamReq.Header.Add(key, value)
+	}
+	if r.Type == RequestGraphQL && len(upstreamReq.Header.Get("Content-Type")) == 0 {
+		upstreamReq.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(upstreamReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError{resp.StatusCode}
+	}
+
+	format := r.format()
+	if err = checkContentType(format, resp.Header.Get("Content-Type")); err != nil {
+		return nil, err
+	}
+
+	b, err := readLimited(resp.Body, p.maxBytes(r))
+	if err != nil {
+		return nil, err
+	}
+	return decode(format, b)
+}
@@ -0,0 +1,32 @@
+package pages
+
+import (
+	"context"
+	"net/http"
+)
+
+// Resolver loads route data from Go code, for example straight from a
+// database, instead of calling an upstream API. Routes reference resolvers by
+// name in "resolve" and their results are appended to the page data after
+// the results of the route Requests.
+type Resolver func(ctx context.Context, r *http.Request, vars map[string]string) (interface{}, error)
+
+// RegisterResolver makes fn available to routes under name. Resolvers must be
+// registered before BuildRouter is called.
+func (p *Pages) RegisterResolver(name string, fn Resolver) {
+	p.resolvers[name] = fn
+}
+
+// withResolvers returns the route's requests followed by a Request for each of
+// its resolvers.
+func withResolvers(route *Route) []Request {
+	if len(route.Resolve) == 0 {
+		return route.Requests
+	}
+	requests := make([]Request, 0, len(route.Requests)+len(route.Resolve))
+	requests = append(requests, route.Requests...)
+	for _, name := range route.Resolve {
+		requests = append(requests, Request{resolver: name})
+	}
+	return requests
+}
@@ -0,0 +1,323 @@
+package pages
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ales6164/raymond"
+	"github.com/aymerick/raymond/ast"
+)
+
+// maxVariadicArgs is the largest number of params a variadic helper accepts.
+const maxVariadicArgs = 16
+
+// variadicHelpers receive all their params as a slice. raymond calls helpers
+// with exactly as many params as they declare, so expandVariadic renames
+// every call to name__N, N being the number of params, and
+// variadicHelperFuncs generates a helper for each N.
+var variadicHelpers = map[string]func(params []interface{}) interface{}{
+	"concat": func(params []interface{}) interface{} {
+		var b strings.Builder
+		for _, v := range params {
+			b.WriteString(raymond.Str(v))
+		}
+		return b.String()
+	},
+	"and": func(params []interface{}) interface{} {
+		for _, v := range params {
+			if !raymond.IsTrue(v) {
+				return false
+			}
+		}
+		return len(params) > 0
+	},
+	"or": func(params []interface{}) interface{} {
+		for _, v := range params {
+			if raymond.IsTrue(v) {
+				return true
+			}
+		}
+		return false
+	},
+}
+
+// expandVariadic rewrites calls of variadic helpers in a template source,
+// see variadicHelpers.
+func expandVariadic(source string) (string, error) {
+	type call struct {
+		pos   int
+		name  string
+		arity int
+	}
+	var calls []call
+	err := walkExpressions(source, func(expr *ast.Expression) {
+		path, ok := expr.Path.(*ast.PathExpression)
+		if !ok || len(expr.Params) == 0 {
+			return
+		}
+		if _, ok := variadicHelpers[path.Original]; ok && strings.HasPrefix(source[path.Pos:], path.Original) {
+			calls = append(calls, call{path.Pos, path.Original, len(expr.Params)})
+		}
+	})
+	if err != nil || len(calls) == 0 {
+		return source, err
+	}
+
+	sort.Slice(calls, func(i, j int) bool { return calls[i].pos > calls[j].pos })
+	for _, c := range calls {
+		if c.arity > maxVariadicArgs {
+			return source, fmt.Errorf("helper %s called with more than %d arguments", c.name, maxVariadicArgs)
+		}
+		source = source[:c.pos] + variadicName(c.name, c.arity) + source[c.pos+len(c.name):]
+	}
+	return source, nil
+}
+
+func variadicName(name string, arity int) string {
+	return name + "__" + strconv.Itoa(arity)
+}
+
+// variadicHelperFuncs returns a helper for every arity of every variadic
+// helper.
+func variadicHelperFuncs() map[string]interface{} {
+	helpers := map[string]interface{}{}
+	out := []reflect.Type{reflect.TypeOf((*interface{})(nil)).Elem()}
+	for name, fn := range variadicHelpers {
+		fn := fn
+		for arity := 1; arity <= maxVariadicArgs; arity++ {
+			in := make([]reflect.Type, arity)
+			for i := range in {
+				in[i] = out[0]
+			}
+			helpers[variadicName(name, arity)] = reflect.MakeFunc(reflect.FuncOf(in, out, false), func(args []reflect.Value) []reflect.Value {
+				params := make([]interface{}, len(args))
+				for i, a := range args {
+					params[i] = a.Interface()
+				}
+				return []reflect.Value{reflect.ValueOf(fn(params)).Convert(out[0])}
+			}).Interface()
+		}
+	}
+	return helpers
+}
+
+// stdHelpers are the general purpose helpers available to every template.
+func stdHelpers() map[string]interface{} {
+	helpers := map[string]interface{}{
+		// comparison
+		"eq": func(a, b interface{}) bool {
+			return compare(a, b) == 0
+		},
+		"ne": func(a, b interface{}) bool {
+			return compare(a, b) != 0
+		},
+		"lt": func(a, b interface{}) bool {
+			return compare(a, b) < 0
+		},
+		"gt": func(a, b interface{}) bool {
+			return compare(a, b) > 0
+		},
+		"not": func(v interface{}) bool {
+			return !raymond.IsTrue(v)
+		},
+
+		// strings
+		"lower": func(s string) string {
+			return strings.ToLower(s)
+		},
+		"upper": func(s string) string {
+			return strings.ToUpper(s)
+		},
+		"truncate": func(s string, length interface{}) string {
+			n := toInt(length)
+			r := []rune(s)
+			if n < 0 || len(r) <= n {
+				return s
+			}
+			return strings.TrimRightFunc(string(r[:n]), unicode.IsSpace) + "…"
+		},
+		"slugify": slugify,
+		"replace": func(s, old, new string) string {
+			return strings.Replace(s, old, new, -1)
+		},
+		"split": func(s, sep string) []string {
+			return strings.Split(s, sep)
+		},
+		"join": func(list interface{}, sep string) string {
+			v := reflect.ValueOf(list)
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return raymond.Str(list)
+			}
+			parts := make([]string, v.Len())
+			for i := range parts {
+				parts[i] = raymond.Str(v.Index(i).Interface())
+			}
+			return strings.Join(parts, sep)
+		},
+
+		// data
+		"json": func(v interface{}) string {
+			d, _ := json.Marshal(v)
+			return string(d)
+		},
+		"default": func(v, fallback interface{}) interface{} {
+			if raymond.IsTrue(v) {
+				return v
+			}
+			return fallback
+		},
+		"len": func(v interface{}) int {
+			rv := reflect.ValueOf(v)
+			switch rv.Kind() {
+			case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+				return rv.Len()
+			}
+			return 0
+		},
+		"range": func(start, end interface{}) []int {
+			var r []int
+			for i := toInt(start); i < toInt(end); i++ {
+				r = append(r, i)
+			}
+			return r
+		},
+
+		// time
+		"now": func() time.Time {
+			return time.Now()
+		},
+		"parseDate": func(value interface{}, layout string) interface{} {
+			t, ok := parseTime(value, layout)
+			if !ok {
+				return nil
+			}
+			return t
+		},
+		"formatDate": func(value interface{}, layout string) string {
+			t, ok := parseTime(value, "")
+			if !ok {
+				return ""
+			}
+			return t.Format(dateLayout(layout))
+		},
+	}
+	for name, fn := range variadicHelperFuncs() {
+		helpers[name] = fn
+	}
+	return helpers
+}
+
+// compare orders numbers numerically and everything else by its string value.
+func compare(a, b interface{}) int {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(raymond.Str(a), raymond.Str(b))
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func toInt(v interface{}) int {
+	if f, ok := toFloat(v); ok {
+		return int(f)
+	}
+	i, _ := strconv.Atoi(raymond.Str(v))
+	return i
+}
+
+var slugReplacer = strings.NewReplacer(
+	"č", "c", "ć", "c", "š", "s", "ž", "z", "đ", "d",
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c", "ß", "ss", "ý", "y", "ÿ", "y",
+)
+
+// slugify lowercases s, transliterates common latin letters and joins the
+// remaining letters and digits with dashes.
+func slugify(s string) string {
+	s = slugReplacer.Replace(strings.ToLower(s))
+	var b strings.Builder
+	dash := false
+	for _, r := range s {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// dateLayouts are names that can be used in place of a Go time layout.
+var dateLayouts = map[string]string{
+	"":         time.RFC3339,
+	"date":     "2006-01-02",
+	"datetime": "2006-01-02 15:04",
+	"time":     "15:04",
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+}
+
+func dateLayout(layout string) string {
+	if l, ok := dateLayouts[layout]; ok {
+		return l
+	}
+	return layout
+}
+
+// parseTime accepts a time.Time, a unix timestamp in seconds or a string in
+// the given layout. Strings are tried as RFC 3339, RFC 1123 and plain dates
+// when layout is empty.
+func parseTime(value interface{}, layout string) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		layouts := []string{dateLayout(layout)}
+		if len(layout) == 0 {
+			layouts = append(layouts, time.RFC3339Nano, time.RFC1123, time.RFC1123Z, "2006-01-02 15:04:05", "2006-01-02")
+		}
+		for _, l := range layouts {
+			if t, err := time.Parse(l, v); err == nil {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+	if f, ok := toFloat(value); ok {
+		return time.Unix(int64(f), 0), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,48 @@
+package pages
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandVariadic(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`{{concat "a" "b"}}`, `{{concat__2 "a" "b"}}`},
+		{`{{concat}}`, `{{concat}}`},
+		{`{{#if (and a (or b c d))}}x{{/if}}`, `{{#if (and__2 a (or__3 b c d))}}x{{/if}}`},
+		{`{{concat (concat a b c) "-" (lower (concat d e))}}`, `{{concat__3 (concat__3 a b c) "-" (lower (concat__2 d e))}}`},
+		{`čćž € {{concat "š" x}} ⌘ {{or a b}}`, `čćž € {{concat__2 "š" x}} ⌘ {{or__2 a b}}`},
+		{`{{! concat a b }}{{!-- {{or a b}} --}}{{concat a b}}`, `{{! concat a b }}{{!-- {{or a b}} --}}{{concat__2 a b}}`},
+		{`concat a b {{upper "concat"}}`, `concat a b {{upper "concat"}}`},
+	}
+	for _, test := range tests {
+		got, err := expandVariadic(test.source)
+		if err != nil {
+			t.Errorf("expandVariadic(%s): %v", test.source, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("expandVariadic(%s) = %s, want %s", test.source, got, test.want)
+		}
+	}
+
+	tooMany := "{{concat" + strings.Repeat(" a", maxVariadicArgs+1) + "}}"
+	if _, err := expandVariadic(tooMany); err == nil {
+		t.Errorf("expandVariadic with %d arguments: no error", maxVariadicArgs+1)
+	}
+}
+
+func TestCustomHelperNames(t *testing.T) {
+	for _, name := range []string{"concat", "and", "or", "concat__2", "my__helper", "eq"} {
+		p := &Pages{Options: &Options{Helpers: map[string]interface{}{
+			name: func(s string) string { return s },
+		}}, Manifest: &Manifest{}, registered: map[string]interface{}{}}
+		err := p.buildHelpers()
+		if err == nil || !strings.Contains(err.Error(), "already registered") {
+			t.Errorf("helper %s: got %v, want already registered", name, err)
+		}
+	}
+}
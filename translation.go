@@ -0,0 +1,168 @@
+package pages
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// translate looks up key in the translations of locale, falling back along
+// localeChain, and formats the message with args. Missing keys render as the
+// key itself.
+func (p *Pages) translate(locale, key string, args map[string]interface{}) string {
+	v, ok := p.lookupTranslation(locale, key)
+	if !ok {
+		return key
+	}
+	if strings.IndexByte(v, '{') >= 0 {
+		if s, err := formatMessage(v, args, locale); err == nil {
+			return s
+		}
+	}
+	return v
+}
+
+func (p *Pages) lookupTranslation(locale, key string) (string, bool) {
+	for _, l := range p.localeChain(locale) {
+		if v, err := p.Manifest.GetResource("translations", l, key); err == nil {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// TranslationReport compares the translation keys used by component templates
+// with Resources.translations. A regional locale is not missing keys its
+// language has. Keys used with a non literal argument can't be
+// extracted and are reported as unused.
+type TranslationReport struct {
+	Used         map[string][]string // key to the components using it
+	Missing      map[string][]string // locale to used keys without a translation
+	Unused       map[string][]string // locale to translated keys no template uses
+	Untranslated map[string][]string // locale to keys that are empty or equal to the default locale value
+}
+
+// HasMissing reports whether any locale lacks a used key.
+func (r *TranslationReport) HasMissing() bool {
+	for _, keys := range r.Missing {
+		if len(keys) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// TranslationKeys returns the literal keys passed to i18n and trans in the
+// component's template. In html templates i18n takes the context first.
+func (c *Component) TranslationKeys() ([]string, error) {
+	i18n := 0
+	if !c.handlebars() {
+		i18n = 1
+	}
+	keys, err := c.literalArgs("i18n", i18n)
+	if err != nil {
+		return keys, err
+	}
+	trans, err := c.literalArgs("trans", 1)
+	return append(keys, trans...), err
+}
+
+// TranslationReport lists missing, unused and untranslated keys of every
+// locale in Resources.translations.
+func (p *Pages) TranslationReport() (*TranslationReport, error) {
+	r := &TranslationReport{
+		Used:         map[string][]string{},
+		Missing:      map[string][]string{},
+		Unused:       map[string][]string{},
+		Untranslated: map[string][]string{},
+	}
+
+	for name, c := range p.Components {
+		keys, err := c.TranslationKeys()
+		if err != nil {
+			return r, errors.New("error parsing component " + name + "; " + err.Error())
+		}
+		for _, key := range keys {
+			r.Used[key] = appendUnique(r.Used[key], name)
+		}
+	}
+
+	var resources struct {
+		Translations map[string]map[string]interface{} `json:"translations"`
+	}
+	if len(p.Manifest.Resources) > 0 {
+		if err := json.Unmarshal(p.Manifest.Resources, &resources); err != nil {
+			return r, err
+		}
+	}
+	defaults := resources.Translations[p.DefaultLocale]
+
+	for locale, translations := range resources.Translations {
+		// a regional locale may rely on its language, sl-SI on sl
+		base := translations
+		if i := strings.IndexAny(locale, "-_"); i > 0 {
+			base = resources.Translations[locale[:i]]
+		}
+		for key := range r.Used {
+			_, ok := translations[key]
+			if _, inBase := base[key]; !ok && !inBase {
+				r.Missing[locale] = append(r.Missing[locale], key)
+			}
+		}
+		for key, v := range translations {
+			if _, ok := r.Used[key]; !ok {
+				r.Unused[locale] = append(r.Unused[locale], key)
+			}
+			s, _ := v.(string)
+			if len(s) == 0 || (locale != p.DefaultLocale && s == defaults[key]) {
+				r.Untranslated[locale] = append(r.Untranslated[locale], key)
+			}
+		}
+		sort.Strings(r.Missing[locale])
+		sort.Strings(r.Unused[locale])
+		sort.Strings(r.Untranslated[locale])
+	}
+	if _, ok := resources.Translations[p.DefaultLocale]; !ok && len(p.DefaultLocale) > 0 {
+		for key := range r.Used {
+			r.Missing[p.DefaultLocale] = append(r.Missing[p.DefaultLocale], key)
+		}
+		sort.Strings(r.Missing[p.DefaultLocale])
+	}
+	for key := range r.Used {
+		sort.Strings(r.Used[key])
+	}
+	return r, nil
+}
+
+// checkTranslations fails when Options.StrictTranslations is set and a used
+// key is missing in any locale.
+func (p *Pages) checkTranslations() error {
+	if !p.StrictTranslations {
+		return nil
+	}
+	r, err := p.TranslationReport()
+	if err != nil {
+		return err
+	}
+	if !r.HasMissing() {
+		return nil
+	}
+	var missing []string
+	for locale, keys := range r.Missing {
+		for _, key := range keys {
+			missing = append(missing, locale+":"+key)
+		}
+	}
+	sort.Strings(missing)
+	return errors.New("missing translations: " + strings.Join(missing, ", "))
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,175 @@
+package pages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Upstream is an entry of Options.AllowedUpstreams. Each field is a path.Match
+// pattern; an empty Scheme matches http and https and an empty Port matches
+// only the default port of the scheme.
+type Upstream struct {
+	Scheme string `json:"scheme"`
+	Host   string `json:"host"`
+	Port   string `json:"port"`
+}
+
+var (
+	ErrUpstreamNotAllowed = errors.New("upstream not allowed")
+	ErrLocalUpstream      = errors.New("upstream resolves to a loopback or link-local address")
+)
+
+func (u Upstream) match(target *url.URL) bool {
+	scheme := strings.ToLower(target.Scheme)
+	if len(u.Scheme) == 0 {
+		if scheme != "http" && scheme != "https" {
+			return false
+		}
+	} else if ok, _ := path.Match(strings.ToLower(u.Scheme), scheme); !ok {
+		return false
+	}
+
+	if ok, _ := path.Match(strings.ToLower(u.Host), strings.ToLower(target.Hostname())); !ok {
+		return false
+	}
+
+	port := target.Port()
+	if len(port) == 0 {
+		port = defaultPort(scheme)
+	}
+	if len(u.Port) == 0 {
+		return port == defaultPort(scheme)
+	}
+	ok, _ := path.Match(u.Port, port)
+	return ok
+}
+
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// checkUpstream is called with the fully interpolated request URL before any
+// connection is made and again for every redirect the upstream responds with.
+func (p *Pages) checkUpstream(target *url.URL) error {
+	if len(p.AllowedUpstreams) == 0 {
+		if target.Scheme != "http" && target.Scheme != "https" {
+			return ErrUpstreamNotAllowed
+		}
+		return nil
+	}
+	for _, u := range p.AllowedUpstreams {
+		if u.match(target) {
+			return nil
+		}
+	}
+	return ErrUpstreamNotAllowed
+}
+
+// isLocalIP reports whether ip is an address that Requests may not reach
+// unless Options.AllowLocalUpstreams is set.
+func isLocalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// newClient returns the client used for all upstream requests. Local
+// addresses are rejected when dialing so a hostname resolving to one is
+// blocked as well. Proxies from the environment are not used, the dialer
+// would check the proxy address instead of the upstream's.
+func (p *Pages) newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   time.Second * 10,
+		KeepAlive: time.Second * 30,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if p.AllowLocalUpstreams {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isLocalIP(ip) {
+				return ErrLocalUpstream
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	return &http.Client{
+		Timeout:   time.Second * 10,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return p.checkUpstream(req.URL)
+		},
+	}
+}
+
+// resolveURL replaces $var placeholders in rawURL with mux vars. Values are
+// path-escaped before the query string and query-escaped after it so that a
+// crafted var can't change the host, path or query of the upstream call.
+func resolveURL(rawURL string, vars map[string]string) string {
+	base, query := rawURL, ""
+	if i := strings.Index(rawURL, "?"); i >= 0 {
+		base, query = rawURL[:i], rawURL[i:]
+	}
+	base = regex.ReplaceAllStringFunc(base, func(s string) string {
+		return url.PathEscape(vars[s[1:]])
+	})
+	query = regex.ReplaceAllStringFunc(query, func(s string) string {
+		return url.QueryEscape(vars[s[1:]])
+	})
+	return base + query
+}
+
+// resolveBody replaces $var placeholders in a JSON body with mux vars. Inside
+// a JSON string a value is escaped as string content, elsewhere it is written
+// as a quoted JSON string, so a crafted var can't add fields to the body.
+func resolveBody(body string, vars map[string]string) string {
+	var b strings.Builder
+	var inString, escaped bool
+	last := 0
+	for _, m := range regex.FindAllStringIndex(body, -1) {
+		for i := last; i < m[0]; i++ {
+			switch c := body[i]; {
+			case escaped:
+				escaped = false
+			case c == '\\' && inString:
+				escaped = true
+			case c == '"':
+				inString = !inString
+			}
+		}
+		b.WriteString(body[last:m[0]])
+
+		quoted, _ := json.Marshal(vars[body[m[0]+1:m[1]]])
+		if inString {
+			quoted = quoted[1 : len(quoted)-1]
+		}
+		b.Write(quoted)
+		last = m[1]
+	}
+	b.WriteString(body[last:])
+	return b.String()
+}
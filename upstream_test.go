@@ -0,0 +1,145 @@
+package pages
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestUpstreamMatch(t *testing.T) {
+	tests := []struct {
+		upstream Upstream
+		target   string
+		want     bool
+	}{
+		{Upstream{Host: "api.example.com"}, "https://api.example.com/items", true},
+		{Upstream{Host: "api.example.com"}, "http://API.example.com/items", true},
+		{Upstream{Host: "api.example.com"}, "https://api.example.com:8443/items", false},
+		{Upstream{Host: "api.example.com", Port: "8443"}, "https://api.example.com:8443/items", true},
+		{Upstream{Host: "api.example.com"}, "ftp://api.example.com/items", false},
+		{Upstream{Host: "*.example.com"}, "https://cdn.example.com/", true},
+		{Upstream{Host: "*.example.com"}, "https://example.com.evil.org/", false},
+		{Upstream{Scheme: "https", Host: "api.example.com"}, "http://api.example.com/", false},
+		{Upstream{Host: "api.example.com", Port: "80*"}, "http://api.example.com:8080/", true},
+	}
+	for _, test := range tests {
+		target, err := url.Parse(test.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := test.upstream.match(target); got != test.want {
+			t.Errorf("%+v.match(%s) = %v, want %v", test.upstream, test.target, got, test.want)
+		}
+	}
+}
+
+func TestCheckUpstream(t *testing.T) {
+	p := &Pages{Options: &Options{}}
+	for target, want := range map[string]error{
+		"https://any.example.com/": nil,
+		"file:///etc/passwd":       ErrUpstreamNotAllowed,
+		"gopher://example.com/":    ErrUpstreamNotAllowed,
+	} {
+		u, _ := url.Parse(target)
+		if err := p.checkUpstream(u); err != want {
+			t.Errorf("checkUpstream(%s) = %v, want %v", target, err, want)
+		}
+	}
+
+	p.AllowedUpstreams = []Upstream{{Host: "api.example.com"}}
+	u, _ := url.Parse("https://other.example.com/")
+	if err := p.checkUpstream(u); err != ErrUpstreamNotAllowed {
+		t.Errorf("checkUpstream(%s) = %v, want %v", u, err, ErrUpstreamNotAllowed)
+	}
+}
+
+func TestResolveURL(t *testing.T) {
+	tests := []struct {
+		raw  string
+		vars map[string]string
+		want string
+	}{
+		{"https://api.example.com/items/$id", map[string]string{"id": "1"}, "https://api.example.com/items/1"},
+		{"https://api.example.com/items/$id", map[string]string{"id": "../admin"}, "https://api.example.com/items/..%2Fadmin"},
+		{"https://api.example.com/items/$id", map[string]string{"id": "1?admin=1#x"}, "https://api.example.com/items/1%3Fadmin=1%23x"},
+		{"https://$host/items", map[string]string{"host": "evil.org/x"}, "https://evil.org%2Fx/items"},
+		{"https://api.example.com/search?q=$q", map[string]string{"q": "a&admin=1"}, "https://api.example.com/search?q=a%26admin%3D1"},
+	}
+	for _, test := range tests {
+		if got := resolveURL(test.raw, test.vars); got != test.want {
+			t.Errorf("resolveURL(%q) = %q, want %q", test.raw, got, test.want)
+		}
+	}
+}
+
+func TestResolveBody(t *testing.T) {
+	vars := map[string]string{"id": `1", "admin": true, "x": "`, "n": "5"}
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"id": "$id"}`, `{"id": "1\", \"admin\": true, \"x\": \""}`},
+		{`{"id": $id}`, `{"id": "1\", \"admin\": true, \"x\": \""}`},
+		{`{"q": "a \"$n\" b", "n": $n}`, `{"q": "a \"5\" b", "n": "5"}`},
+		{`{"missing": "$none"}`, `{"missing": ""}`},
+	}
+	for _, test := range tests {
+		if got := resolveBody(test.body, vars); got != test.want {
+			t.Errorf("resolveBody(%s) = %s, want %s", test.body, got, test.want)
+		}
+	}
+}
+
+func TestClientBlocksLocalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	p := &Pages{Options: &Options{}}
+	_, err := p.newClient().Get(server.URL)
+	if !errors.Is(err, ErrLocalUpstream) {
+		t.Errorf("request to %s: got %v, want %v", server.URL, err, ErrLocalUpstream)
+	}
+
+	p.AllowLocalUpstreams = true
+	resp, err := p.newClient().Get(server.URL)
+	if err != nil {
+		t.Fatalf("request to %s with AllowLocalUpstreams: %v", server.URL, err)
+	}
+	resp.Body.Close()
+}
+
+func TestClientIgnoresProxy(t *testing.T) {
+	var proxied bool
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = true
+	}))
+	defer proxy.Close()
+
+	for _, key := range []string{"HTTP_PROXY", "http_proxy"} {
+		old, ok := os.LookupEnv(key)
+		os.Setenv(key, proxy.URL)
+		if ok {
+			defer os.Setenv(key, old)
+		} else {
+			defer os.Unsetenv(key)
+		}
+	}
+
+	p := &Pages{Options: &Options{}}
+	_, err := p.newClient().Get("http://169.254.169.254/latest/meta-data/")
+	if !errors.Is(err, ErrLocalUpstream) {
+		t.Errorf("request with a proxy configured: got %v, want %v", err, ErrLocalUpstream)
+	}
+	// the proxy listens on loopback, a public upstream must not be blocked
+	// because of it
+	_, err = p.newClient().Get("http://upstream.invalid/")
+	if errors.Is(err, ErrLocalUpstream) {
+		t.Errorf("request with a loopback proxy configured: got %v", err)
+	}
+	if proxied {
+		t.Error("request was sent to the proxy")
+	}
+}
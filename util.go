@@ -2,11 +2,11 @@ package pages
 
 import (
 	"encoding/json"
-	"io/ioutil"
+	"io/fs"
 )
 
-func readAndUnmarshal(filePath string, v interface{}) error {
-	file, err := ioutil.ReadFile(filePath)
+func readAndUnmarshal(fsys fs.FS, filePath string, v interface{}) error {
+	file, err := fs.ReadFile(fsys, filePath)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,107 @@
+package pages
+
+import (
+	"github.com/aymerick/raymond/ast"
+	"github.com/aymerick/raymond/parser"
+)
+
+// walkExpressions parses a template source and calls fn for every
+// expression in it, including block, partial and sub-expressions.
+func walkExpressions(source string, fn func(*ast.Expression)) error {
+	program, err := parser.Parse(source)
+	if err != nil {
+		return err
+	}
+	program.Accept(&exprVisitor{fn: fn})
+	return nil
+}
+
+// walkPartials parses a template source and calls fn with the name of every
+// partial it includes by a literal name.
+func walkPartials(source string, fn func(name string)) error {
+	program, err := parser.Parse(source)
+	if err != nil {
+		return err
+	}
+	program.Accept(&exprVisitor{fn: func(*ast.Expression) {}, partial: fn})
+	return nil
+}
+
+type exprVisitor struct {
+	fn      func(*ast.Expression)
+	partial func(name string)
+}
+
+func (v *exprVisitor) VisitProgram(node *ast.Program) interface{} {
+	for _, n := range node.Body {
+		n.Accept(v)
+	}
+	return nil
+}
+
+func (v *exprVisitor) VisitMustache(node *ast.MustacheStatement) interface{} {
+	node.Expression.Accept(v)
+	return nil
+}
+
+func (v *exprVisitor) VisitBlock(node *ast.BlockStatement) interface{} {
+	node.Expression.Accept(v)
+	if node.Program != nil {
+		node.Program.Accept(v)
+	}
+	if node.Inverse != nil {
+		node.Inverse.Accept(v)
+	}
+	return nil
+}
+
+func (v *exprVisitor) VisitPartial(node *ast.PartialStatement) interface{} {
+	if name, ok := ast.HelperNameStr(node.Name); ok && v.partial != nil {
+		v.partial(name)
+	}
+	node.Name.Accept(v)
+	for _, n := range node.Params {
+		n.Accept(v)
+	}
+	if node.Hash != nil {
+		node.Hash.Accept(v)
+	}
+	return nil
+}
+
+func (v *exprVisitor) VisitContent(node *ast.ContentStatement) interface{} { return nil }
+func (v *exprVisitor) VisitComment(node *ast.CommentStatement) interface{} { return nil }
+
+func (v *exprVisitor) VisitExpression(node *ast.Expression) interface{} {
+	v.fn(node)
+	node.Path.Accept(v)
+	for _, n := range node.Params {
+		n.Accept(v)
+	}
+	if node.Hash != nil {
+		node.Hash.Accept(v)
+	}
+	return nil
+}
+
+func (v *exprVisitor) VisitSubExpression(node *ast.SubExpression) interface{} {
+	node.Expression.Accept(v)
+	return nil
+}
+
+func (v *exprVisitor) VisitPath(node *ast.PathExpression) interface{}    { return nil }
+func (v *exprVisitor) VisitString(node *ast.StringLiteral) interface{}   { return nil }
+func (v *exprVisitor) VisitBoolean(node *ast.BooleanLiteral) interface{} { return nil }
+func (v *exprVisitor) VisitNumber(node *ast.NumberLiteral) interface{}   { return nil }
+
+func (v *exprVisitor) VisitHash(node *ast.Hash) interface{} {
+	for _, pair := range node.Pairs {
+		pair.Accept(v)
+	}
+	return nil
+}
+
+func (v *exprVisitor) VisitHashPair(node *ast.HashPair) interface{} {
+	node.Val.Accept(v)
+	return nil
+}